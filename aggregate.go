@@ -0,0 +1,275 @@
+package pgsql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/rest-layer/rest"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+)
+
+// The functions of an Aggregation.
+const (
+	AggregateCount = "count"
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+)
+
+// Aggregation is a value computed over the items of each group.
+type Aggregation struct {
+	// Name is the key of the value in AggregateRow.Values.
+	Name string
+	// Func is one of the Aggregate* functions.
+	Func string
+	// Field is the aggregated field, or empty for a count of the items.
+	Field string
+}
+
+// AggregateSpec describes the groups of an Aggregate and the values computed
+// for each of them.
+type AggregateSpec struct {
+	// GroupBy are the fields, possibly dotted paths, the items are grouped
+	// by. All the items are in a single group when empty.
+	GroupBy []string
+	// Aggregations are the values computed for each group.
+	Aggregations []Aggregation
+}
+
+// Validate checks that the fields of spec exist in s and are not hidden,
+// that its group fields are filterable, that it only sums and averages
+// numeric fields and that its aggregations are valid and uniquely named.
+func (spec AggregateSpec) Validate(s *schema.Schema) error {
+	if len(spec.Aggregations) == 0 {
+		return fmt.Errorf("aggregate: no aggregation")
+	}
+	for _, field := range spec.GroupBy {
+		f := s.GetField(field)
+		if f == nil || f.Hidden {
+			return fmt.Errorf("aggregate: unknown group field %s", field)
+		}
+		if !f.Filterable {
+			return fmt.Errorf("aggregate: group field %s is not filterable", field)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, a := range spec.Aggregations {
+		if a.Name == "" || names[a.Name] {
+			return fmt.Errorf("aggregate: missing or duplicate name %q", a.Name)
+		}
+		names[a.Name] = true
+
+		switch a.Func {
+		case AggregateCount:
+			if a.Field == "" {
+				continue
+			}
+		case AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+			if a.Field == "" {
+				return fmt.Errorf("aggregate: %s: %s needs a field", a.Name, a.Func)
+			}
+		default:
+			return fmt.Errorf("aggregate: %s: unknown function %s", a.Name, a.Func)
+		}
+		f := s.GetField(a.Field)
+		if f == nil || f.Hidden {
+			return fmt.Errorf("aggregate: %s: unknown field %s", a.Name, a.Field)
+		}
+		if (a.Func == AggregateSum || a.Func == AggregateAvg) && !numeric(f.Validator) {
+			return fmt.Errorf("aggregate: %s: %s needs a numeric field, not %s", a.Name, a.Func, a.Field)
+		}
+	}
+	return nil
+}
+
+// numericTypes are the prefixes of the numeric Postgres types.
+var numericTypes = []string{"SMALLINT", "INTEGER", "INT", "BIGINT", "SERIAL", "BIGSERIAL", "SMALLSERIAL",
+	"NUMERIC", "DECIMAL", "REAL", "DOUBLE PRECISION", "FLOAT"}
+
+// numeric tells if the values of v are numbers: integers, floats, or of a
+// numeric Postgres type.
+func numeric(v schema.FieldValidator) bool {
+	switch v := v.(type) {
+	case *schema.Integer, schema.Integer, *schema.Float, schema.Float:
+		return true
+	case PostgresTyper:
+		pgType := strings.ToUpper(v.PostgresType())
+		for _, prefix := range numericTypes {
+			if pgType == prefix || strings.HasPrefix(pgType, prefix+"(") || strings.HasPrefix(pgType, prefix+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AggregateRow is a group of an Aggregate.
+type AggregateRow struct {
+	// Group holds the values of the group fields, by field.
+	Group map[string]any `json:"group"`
+	// Values holds the aggregated values, by aggregation name. Counts are
+	// int64, sums of integers int64 and other sums and averages float64.
+	// Minimums and maximums have the type of their field.
+	Values map[string]any `json:"values"`
+}
+
+// Aggregator is implemented by the stores computing aggregations.
+type Aggregator interface {
+	// Aggregate groups the items matching the predicate of q and computes
+	// the aggregations of spec for each group. The groups are sorted by the
+	// sort of q, whose fields are group fields or aggregation names, and
+	// windowed by its window.
+	Aggregate(ctx context.Context, q *query.Query, spec AggregateSpec) ([]AggregateRow, error)
+}
+
+var aggregationExpr = regexp.MustCompile(`^(?:([^:()]+):)?(\w+)\(([^()]*)\)$`)
+
+// ParseAggregateSpec parses the comma separated group fields and
+// aggregations of an AggregateSpec. An aggregation is written
+// [name:]func(field), e.g. total:sum(amount) or count(), and is named
+// func_field, or func, by default.
+func ParseAggregateSpec(groupBy, aggregations string) (AggregateSpec, error) {
+	var spec AggregateSpec
+	for _, field := range strings.Split(groupBy, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			spec.GroupBy = append(spec.GroupBy, field)
+		}
+	}
+
+	for _, expr := range strings.Split(aggregations, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		m := aggregationExpr.FindStringSubmatch(expr)
+		if m == nil {
+			return AggregateSpec{}, fmt.Errorf("aggregate: invalid aggregation %q", expr)
+		}
+		a := Aggregation{Name: m[1], Func: strings.ToLower(m[2]), Field: strings.TrimSpace(m[3])}
+		if a.Name == "" {
+			a.Name = a.Func
+			if a.Field != "" {
+				a.Name += "_" + strings.ReplaceAll(a.Field, ".", "_")
+			}
+		}
+		spec.Aggregations = append(spec.Aggregations, a)
+	}
+	return spec, nil
+}
+
+// NewAggregateHandler returns a handler exposing the aggregations of a store
+// of items of schema s over HTTP. It answers GET requests with the JSON array
+// of the AggregateRows, reading:
+//
+//   - group: the comma separated group fields
+//   - aggregate: the aggregations, as parsed by ParseAggregateSpec
+//   - filter: a rest-layer predicate on the items
+//   - sort: the rest-layer sort of the groups
+//   - limit and skip: the window of the groups
+//
+// Errors are reported as rest-layer reports them.
+//
+// The handler calls agg directly, bypassing the rest-layer resource: neither
+// its hooks nor its permissions apply, and the handler must be guarded, e.g.
+// by a middleware, like any other route of the application.
+func NewAggregateHandler(agg Aggregator, s *schema.Schema) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAggregateError(w, rest.ErrInvalidMethod)
+			return
+		}
+
+		q, spec, err := parseAggregateRequest(r, s)
+		if err != nil {
+			writeAggregateError(w, &rest.Error{Code: http.StatusUnprocessableEntity, Message: err.Error()})
+			return
+		}
+
+		rows, err := agg.Aggregate(r.Context(), q, spec)
+		if err != nil {
+			writeAggregateError(w, rest.NewError(err))
+			return
+		}
+		if rows == nil {
+			rows = []AggregateRow{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	})
+}
+
+func parseAggregateRequest(r *http.Request, s *schema.Schema) (*query.Query, AggregateSpec, error) {
+	params := r.URL.Query()
+
+	spec, err := ParseAggregateSpec(params.Get("group"), params.Get("aggregate"))
+	if err != nil {
+		return nil, AggregateSpec{}, err
+	}
+	if err := spec.Validate(s); err != nil {
+		return nil, AggregateSpec{}, err
+	}
+
+	var window *query.Window
+	if params.Has("limit") || params.Has("skip") {
+		window = &query.Window{Limit: -1}
+		for name, value := range map[string]*int{"limit": &window.Limit, "skip": &window.Offset} {
+			if !params.Has(name) {
+				continue
+			}
+			n, err := strconv.Atoi(params.Get(name))
+			if err != nil || n < 0 {
+				return nil, AggregateSpec{}, fmt.Errorf("invalid `%s` parameter", name)
+			}
+			*value = n
+		}
+	}
+
+	q, err := query.New("", params.Get("filter"), params.Get("sort"), window)
+	if err != nil {
+		return nil, AggregateSpec{}, err
+	}
+	if err := q.Predicate.Prepare(s); err != nil {
+		return nil, AggregateSpec{}, err
+	}
+	for _, sort := range q.Sort {
+		if !spec.Sorts(sort.Name) {
+			return nil, AggregateSpec{}, fmt.Errorf("invalid sort field: %s", sort.Name)
+		}
+	}
+	return q, spec, nil
+}
+
+// Sorts tells if the groups of spec can be sorted by name, a group field or
+// an aggregation name.
+func (spec AggregateSpec) Sorts(name string) bool {
+	for _, field := range spec.GroupBy {
+		if field == name {
+			return true
+		}
+	}
+	for _, a := range spec.Aggregations {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func writeAggregateError(w http.ResponseWriter, err *rest.Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.Code)
+	body := map[string]any{"code": err.Code, "message": err.Message}
+	if len(err.Issues) > 0 {
+		body["issues"] = err.Issues
+	}
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,153 @@
+package pgsql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+)
+
+func TestParseAggregateSpec(t *testing.T) {
+	spec, err := ParseAggregateSpec("status, address.city", "count(), total:sum(amount),AVG(address.zip)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := AggregateSpec{
+		GroupBy: []string{"status", "address.city"},
+		Aggregations: []Aggregation{
+			{Name: "count", Func: AggregateCount},
+			{Name: "total", Func: AggregateSum, Field: "amount"},
+			{Name: "avg_address_zip", Func: AggregateAvg, Field: "address.zip"},
+		},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("Spec = %#v, want %#v", spec, want)
+	}
+
+	if _, err := ParseAggregateSpec("", "sum(amount"); err == nil {
+		t.Error("Expected an error for an invalid aggregation")
+	}
+}
+
+type aggregatorFunc func(ctx context.Context, q *query.Query, spec AggregateSpec) ([]AggregateRow, error)
+
+func (f aggregatorFunc) Aggregate(ctx context.Context, q *query.Query, spec AggregateSpec) ([]AggregateRow, error) {
+	return f(ctx, q, spec)
+}
+
+func TestAggregateHandler(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"status": {Validator: &schema.String{}, Filterable: true},
+			"amount": {Validator: &schema.Integer{}},
+			"note":   {Validator: &schema.String{}},
+			"secret": {Validator: &schema.String{}, Filterable: true, Hidden: true},
+		},
+	}
+	var got *query.Query
+	h := NewAggregateHandler(aggregatorFunc(func(ctx context.Context, q *query.Query, spec AggregateSpec) ([]AggregateRow, error) {
+		got = q
+		return []AggregateRow{{
+			Group:  map[string]any{"status": "paid"},
+			Values: map[string]any{"total": int64(42)},
+		}}, nil
+	}), sc)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "ok",
+			url:      `/?group=status&aggregate=total:sum(amount)&filter={status:"paid"}&sort=-total&limit=5`,
+			wantCode: http.StatusOK,
+			wantBody: `[{"group":{"status":"paid"},"values":{"total":42}}]`,
+		},
+		{
+			name:     "unknown field",
+			url:      `/?aggregate=sum(price)`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"code":422,"message":"aggregate: sum_price: unknown field price"}`,
+		},
+		{
+			name:     "hidden group field",
+			url:      `/?group=secret&aggregate=count()`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"code":422,"message":"aggregate: unknown group field secret"}`,
+		},
+		{
+			name:     "hidden field",
+			url:      `/?aggregate=max(secret)`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"code":422,"message":"aggregate: max_secret: unknown field secret"}`,
+		},
+		{
+			name:     "unfilterable group field",
+			url:      `/?group=note&aggregate=count()`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"code":422,"message":"aggregate: group field note is not filterable"}`,
+		},
+		{
+			name:     "sum of strings",
+			url:      `/?aggregate=sum(status)`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"code":422,"message":"aggregate: sum_status: sum needs a numeric field, not status"}`,
+		},
+		{
+			name:     "invalid sort",
+			url:      `/?aggregate=count()&sort=amount`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"code":422,"message":"invalid sort field: amount"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("Code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.wantBody {
+				t.Errorf("Body = %s, want %s", body, tt.wantBody)
+			}
+		})
+	}
+
+	if got == nil || len(got.Predicate) != 1 || got.Window == nil || got.Window.Limit != 5 || got.Sort[0].Name != "total" || !got.Sort[0].Reversed {
+		t.Errorf("Query = %#v, want the filter, sort and window of the request", got)
+	}
+}
+
+func TestAggregateSpec_Validate(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"price": {Validator: numericTyper{}},
+			"score": {Validator: &schema.Float{}},
+			"name":  {Validator: &schema.String{}},
+		},
+	}
+	for _, field := range []string{"price", "score"} {
+		spec := AggregateSpec{Aggregations: []Aggregation{{Name: "avg", Func: AggregateAvg, Field: field}}}
+		if err := spec.Validate(sc); err != nil {
+			t.Errorf("Validate(avg(%s)) = %v, want nil", field, err)
+		}
+	}
+	spec := AggregateSpec{Aggregations: []Aggregation{{Name: "max", Func: AggregateMax, Field: "name"}}}
+	if err := spec.Validate(sc); err != nil {
+		t.Errorf("Validate(max(name)) = %v, want nil", err)
+	}
+}
+
+// numericTyper is a validator of NUMERIC values.
+type numericTyper struct {
+	schema.String
+}
+
+func (numericTyper) PostgresType() string { return "NUMERIC(10,2)" }
@@ -0,0 +1,274 @@
+package pgsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// The predicates below extend the rest-layer ones on array fields, whose
+// semantics the stores define as:
+//
+//   - Equal: the array contains the value, or all the values of an array
+//   - NotEqual: the negation of Equal, matching items without the array
+//   - In: the array contains any of the values, or all the values of one of
+//     the arrays
+//   - NotIn: the negation of In
+//
+// Elements are compared as JSON values, so arrays of numbers or objects are
+// matched by value, and an object element contains the given objects it is a
+// superset of.
+
+// WithNativeArrays makes the classic store keep the top level arrays of
+// strings, integers, floats, booleans, times and PostgresTyper values in
+// native array columns, e.g. TEXT[] or BIGINT[], indexed with GIN, instead of
+// JSONB ones. The predicates keep their semantics. Existing JSONB columns are
+// not converted.
+func WithNativeArrays() Option {
+	return func(o *Options) {
+		o.NativeArrays = true
+	}
+}
+
+// ContainsAll matches the items whose array Field contains all of Values.
+type ContainsAll struct {
+	Field  string
+	Values []any
+}
+
+// Match implements query.Expression.
+func (e ContainsAll) Match(payload map[string]any) bool {
+	array, ok := arrayField(payload, e.Field)
+	if !ok {
+		return false
+	}
+	for _, v := range e.Values {
+		if !containsElement(array, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Prepare implements query.Expression.
+func (e *ContainsAll) Prepare(validator schema.Validator) error {
+	return prepareElements(e.Field, e.Values, validator)
+}
+
+// String implements query.Expression.
+func (e ContainsAll) String() string {
+	return arrayPredicateString(e.Field, "$all", e.Values)
+}
+
+// ContainsAny matches the items whose array Field contains any of Values.
+type ContainsAny struct {
+	Field  string
+	Values []any
+}
+
+// Match implements query.Expression.
+func (e ContainsAny) Match(payload map[string]any) bool {
+	array, ok := arrayField(payload, e.Field)
+	if !ok {
+		return false
+	}
+	for _, v := range e.Values {
+		if containsElement(array, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Prepare implements query.Expression.
+func (e *ContainsAny) Prepare(validator schema.Validator) error {
+	return prepareElements(e.Field, e.Values, validator)
+}
+
+// String implements query.Expression.
+func (e ContainsAny) String() string {
+	return arrayPredicateString(e.Field, "$any", e.Values)
+}
+
+// The comparisons of Size.
+const (
+	SizeEqual          = "="
+	SizeNotEqual       = "!="
+	SizeLowerThan      = "<"
+	SizeLowerOrEqual   = "<="
+	SizeGreaterThan    = ">"
+	SizeGreaterOrEqual = ">="
+)
+
+// Size matches the items whose array Field has a number of elements
+// comparing to Value with Op, one of the Size* comparisons.
+type Size struct {
+	Field string
+	Op    string
+	Value int
+}
+
+// Match implements query.Expression.
+func (e Size) Match(payload map[string]any) bool {
+	array, ok := arrayField(payload, e.Field)
+	if !ok {
+		return false
+	}
+	switch n := len(array); e.Op {
+	case SizeEqual:
+		return n == e.Value
+	case SizeNotEqual:
+		return n != e.Value
+	case SizeLowerThan:
+		return n < e.Value
+	case SizeLowerOrEqual:
+		return n <= e.Value
+	case SizeGreaterThan:
+		return n > e.Value
+	case SizeGreaterOrEqual:
+		return n >= e.Value
+	}
+	return false
+}
+
+// Prepare implements query.Expression.
+func (e *Size) Prepare(validator schema.Validator) error {
+	switch e.Op {
+	case SizeEqual, SizeNotEqual, SizeLowerThan, SizeLowerOrEqual, SizeGreaterThan, SizeGreaterOrEqual:
+	default:
+		return fmt.Errorf("%s: invalid size comparison %q", e.Field, e.Op)
+	}
+	_, err := arrayValidator(e.Field, validator)
+	return err
+}
+
+// String implements query.Expression.
+func (e Size) String() string {
+	return fmt.Sprintf("%s: {$size: {%q: %d}}", e.Field, e.Op, e.Value)
+}
+
+func arrayValidator(field string, validator schema.Validator) (*schema.Array, error) {
+	f := validator.GetField(field)
+	if f == nil {
+		return nil, fmt.Errorf("%s: unknown query field", field)
+	}
+	if !f.Filterable {
+		return nil, fmt.Errorf("%s: field is not filterable", field)
+	}
+	array, ok := f.Validator.(*schema.Array)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an array", field)
+	}
+	return array, nil
+}
+
+func prepareElements(field string, values []any, validator schema.Validator) error {
+	array, err := arrayValidator(field, validator)
+	if err != nil {
+		return err
+	}
+	if array.Values.Validator == nil {
+		return nil
+	}
+
+	validate := array.Values.Validator.Validate
+	if qv, ok := array.Values.Validator.(schema.FieldQueryValidator); ok {
+		validate = qv.ValidateQuery
+	}
+	for i, v := range values {
+		nv, err := validate(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid query expression `%#v': %v", field, v, err)
+		}
+		values[i] = nv
+	}
+	return nil
+}
+
+func arrayField(payload map[string]any, field string) ([]any, bool) {
+	var value any = payload
+	for _, name := range strings.Split(field, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value = m[name]
+	}
+	array, ok := value.([]any)
+	return array, ok
+}
+
+// containsElement tells if an element of array contains v, both compared as
+// JSON values.
+func containsElement(array []any, v any) bool {
+	elements, value := jsonValue(array).([]any), jsonValue(v)
+	for _, element := range elements {
+		if containsJSON(element, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValue returns v as decoded from its JSON encoding, e.g. with float64
+// numbers, or v itself when it cannot be encoded.
+func jsonValue(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return v
+	}
+	return decoded
+}
+
+// containsJSON tells if the decoded JSON value a contains b, like the jsonb
+// @> operator: objects contain their subsets, arrays the arrays of elements
+// they contain, and scalars the equal scalars.
+func containsJSON(a, b any) bool {
+	switch b := b.(type) {
+	case map[string]any:
+		object, ok := a.(map[string]any)
+		if !ok {
+			return false
+		}
+		for name, v := range b {
+			if element, ok := object[name]; !ok || !containsJSON(element, v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		array, ok := a.([]any)
+		if !ok {
+			return false
+		}
+		for _, v := range b {
+			found := false
+			for _, element := range array {
+				if containsJSON(element, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func arrayPredicateString(field, op string, values []any) string {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Sprintf("%s: {%s: %v}", field, op, values)
+	}
+	return fmt.Sprintf("%s: {%s: %s}", field, op, b)
+}
@@ -0,0 +1,67 @@
+package pgsql
+
+import (
+	"testing"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+func TestArrayPredicates_Match(t *testing.T) {
+	payload := map[string]any{
+		"tags": []any{"red", "blue"},
+		"name": "john",
+		// Decoded from the database, and validated by the Integer validator
+		"scores": []any{float64(1), 2.5},
+		"ids":    []any{1, 2},
+		"assets": []any{map[string]any{"kind": "car", "year": float64(2020)}},
+	}
+	tests := []struct {
+		name string
+		e    interface{ Match(map[string]any) bool }
+		want bool
+	}{
+		{"ContainsAll", ContainsAll{Field: "tags", Values: []any{"red", "blue"}}, true},
+		{"ContainsAll: missing", ContainsAll{Field: "tags", Values: []any{"red", "green"}}, false},
+		{"ContainsAny", ContainsAny{Field: "tags", Values: []any{"green", "blue"}}, true},
+		{"ContainsAny: none", ContainsAny{Field: "tags", Values: []any{"green"}}, false},
+		{"ContainsAny: not an array", ContainsAny{Field: "name", Values: []any{"john"}}, false},
+		{"ContainsAll: numbers", ContainsAll{Field: "scores", Values: []any{1, 2.5}}, true},
+		{"ContainsAll: integers", ContainsAll{Field: "ids", Values: []any{float64(1), int64(2)}}, true},
+		{"ContainsAny: numbers", ContainsAny{Field: "scores", Values: []any{3, 1}}, true},
+		{"ContainsAny: object subset", ContainsAny{Field: "assets", Values: []any{map[string]any{"year": 2020}}}, true},
+		{"ContainsAny: object mismatch", ContainsAny{Field: "assets", Values: []any{map[string]any{"kind": "bike"}}}, false},
+		{"Size", Size{Field: "tags", Op: SizeEqual, Value: 2}, true},
+		{"Size: greater", Size{Field: "tags", Op: SizeGreaterThan, Value: 2}, false},
+		{"Size: no array", Size{Field: "other", Op: SizeGreaterOrEqual, Value: 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.Match(payload); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArrayPredicates_Prepare(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"name": {Validator: &schema.String{}, Filterable: true},
+			"tags": {Filterable: true, Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.Integer{}},
+			}},
+		},
+	}
+	if err := (&ContainsAll{Field: "tags", Values: []any{1, 2}}).Prepare(sc); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := (&ContainsAny{Field: "tags", Values: []any{"red"}}).Prepare(sc); err == nil {
+		t.Error("Expected an error for an invalid element")
+	}
+	if err := (&Size{Field: "name", Op: SizeEqual}).Prepare(sc); err == nil {
+		t.Error("Expected an error for a field not an array")
+	}
+	if err := (&Size{Field: "tags", Op: "~"}).Prepare(sc); err == nil {
+		t.Error("Expected an error for an invalid comparison")
+	}
+}
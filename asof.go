@@ -0,0 +1,33 @@
+package pgsql
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/schema/query"
+)
+
+type asOfKey struct{}
+
+// NewAsOfContext returns a context making Find, Count and Reduce of history
+// enabled stores answer against the state of the collection at time at. The
+// state is rebuilt from the history table, so changes made before history was
+// enabled are not visible.
+func NewAsOfContext(ctx context.Context, at time.Time) context.Context {
+	return context.WithValue(ctx, asOfKey{}, at)
+}
+
+// AsOfFromContext returns the time stored by NewAsOfContext, if any.
+func AsOfFromContext(ctx context.Context) (time.Time, bool) {
+	at, ok := ctx.Value(asOfKey{}).(time.Time)
+	return at, ok
+}
+
+// AsOfReader is implemented by stores created with WithHistory.
+type AsOfReader interface {
+	// FindAsOf is Find against the state of the collection at time at.
+	FindAsOf(ctx context.Context, q *query.Query, at time.Time) (*resource.ItemList, error)
+	// CountAsOf is Count against the state of the collection at time at.
+	CountAsOf(ctx context.Context, q *query.Query, at time.Time) (int, error)
+}
@@ -2,51 +2,75 @@ package pgsql
 
 import (
 	"context"
-	"database/sql"
-	"log/slog"
+	"time"
 
 	. "github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/rs/rest-layer/schema"
 	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
 func (s store) Clear(ctx context.Context, q *query.Query) (count int, err error) {
-	var tx *sql.Tx
+	defer internal.Measure(s.opts, s.table, "Clear", time.Now(), &err)
 
-	tx, err = s.db.Begin()
+	ctx = internal.WithQuery(ctx, q)
+	var removal exp.AppendableExpression
+	if s.opts.SoftDelete {
+		builder := s.dialect.Update(s.table)
+		err = buildSoftDeleteWheres(s.schema, s.opts, q, builder)
+		removal = builder.Returning(Star())
+	} else {
+		builder := s.dialect.Delete(s.table)
+		err = buildDeleteWheres(s.schema, s.opts, q, builder)
+		removal = builder.Returning(Star())
+	}
 	if err != nil {
-		return 0, err
+		return
 	}
 
-	builder := s.dialect.Delete(s.table)
-
-	buildDeleteWheres(s.schema, q, builder)
-
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	payload := L(`to_jsonb(?) - '_etag' - '_updated' - '`+pgsql.DeletedColumn+`' - '`+pgsql.SearchColumn+`'`, I("removed"))
+	sqlStr, args, err := internal.PrepareClear(ctx, s.table, s.opts, removal, "_etag", "_updated", payload)
 	if err != nil {
 		return
 	}
 
-	slog.DebugContext(ctx, "pgsql.Clear", "sql", sqlStr, "args", args)
+	err = s.exec.RunTx(ctx, "Clear", func(ctx context.Context, q internal.Querier) error {
+		changes, err := internal.ExecClear(ctx, q, s.table, sqlStr, args)
+		if err != nil {
+			return err
+		}
+		count = len(changes)
 
-	res, err := tx.ExecContext(ctx, sqlStr, args...)
+		if err := internal.WriteEvents(ctx, q, s.opts, changes...); err != nil {
+			return err
+		}
+		return internal.Publish(ctx, q, s.opts, changes...)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	cnt, err := res.RowsAffected()
+	return count, nil
+}
+
+func buildDeleteWheres(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *DeleteDataset) error {
+	expressions, err := predicteToExpressions("", s, opts, q.Predicate)
 	if err != nil {
-		return 0, err
+		return err
 	}
+	*builder = *builder.Where(expressions...)
+	return nil
+}
 
-	err = tx.Commit()
+func buildSoftDeleteWheres(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *UpdateDataset) error {
+	expressions, err := predicteToExpressions("", s, opts, q.Predicate)
 	if err != nil {
-		return
+		return err
 	}
-
-	return int(cnt), err
-}
-func buildDeleteWheres(s *schema.Schema, q *query.Query, builder *DeleteDataset) {
-	expressions := predicteToExpressions(s, q.Predicate)
-	*builder = *builder.Where(expressions...)
+	expressions = append(expressions, C(pgsql.DeletedColumn).IsNull())
+	*builder = *builder.Set(internal.SoftDeleteRecord()).Where(expressions...)
+	return nil
 }
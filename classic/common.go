@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 
+	"github.com/lib/pq"
 	"github.com/rs/rest-layer/schema"
 )
 
@@ -25,3 +26,12 @@ func toJsonString(jsonFields schema.Fields, row map[string]any) error {
 
 	return nil
 }
+
+// toNativeArrays encodes the values of the native array columns of row.
+func toNativeArrays(arrayFields schema.Fields, row map[string]any) {
+	for name := range arrayFields {
+		if col, ok := row[name]; ok && col != nil {
+			row[name] = pq.Array(col)
+		}
+	}
+}
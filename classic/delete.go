@@ -2,34 +2,45 @@ package pgsql
 
 import (
 	"context"
-	"log/slog"
+	"time"
 
 	. "github.com/doug-martin/goqu/v9"
 	"github.com/rs/rest-layer/resource"
-	"github.com/rs/rest-layer/rest"
-)
 
-func (s store) Delete(ctx context.Context, item *resource.Item) error {
-	sqlStr, args, err := s.dialect.Delete(s.table).Where(L("id").Eq(item.ID), L("_etag").Eq(item.ETag)).Prepared(true).ToSQL()
-	if err != nil {
-		return err
-	}
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
 
-	slog.DebugContext(ctx, "pgsql.Delete", "sql", sqlStr, "args", args)
+func (s store) Delete(ctx context.Context, item *resource.Item) (err error) {
+	defer internal.Measure(s.opts, s.table, "Delete", time.Now(), &err)
 
-	affect, err := s.db.ExecContext(ctx, sqlStr, args...)
-	if err != nil {
-		return err
+	var sqlStr string
+	var args []any
+	if s.opts.SoftDelete {
+		sqlStr, args, err = s.dialect.Update(s.table).Set(internal.SoftDeleteRecord()).
+			Where(L("id").Eq(item.ID), L("_etag").Eq(item.ETag), C(pgsql.DeletedColumn).IsNull()).Prepared(true).ToSQL()
+	} else {
+		sqlStr, args, err = s.dialect.Delete(s.table).Where(L("id").Eq(item.ID), L("_etag").Eq(item.ETag)).Prepared(true).ToSQL()
 	}
-
-	count, err := affect.RowsAffected()
 	if err != nil {
 		return err
 	}
 
-	if count != 1 {
-		return rest.ErrPreconditionFailed
-	}
+	return s.exec.RunWrite(ctx, "Delete", func(ctx context.Context, q internal.Querier) error {
+		affect, err := q.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := affect.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return internal.Conflict(ctx, q, s.table, "_etag", item.ID, internal.Live(s.opts)...)
+		}
 
-	return nil
+		return internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationDelete, item, nil)
+	})
 }
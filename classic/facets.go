@@ -0,0 +1,47 @@
+package pgsql
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Facets(ctx context.Context, q *query.Query, fields []string) (map[string][]pgsql.FacetValue, error) {
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	builder := s.dialect.From(source)
+	if err := buildWheres(s.schema, s.opts, q, builder); err != nil {
+		return nil, errors.Wrapf(err, "predicate: %v", q.Predicate)
+	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
+	if err := internal.PrepareFacets(builder, s.table, s.schema, s.opts, fields, s.typedValue, s.jsonValue); err != nil {
+		return nil, err
+	}
+
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var facets map[string][]pgsql.FacetValue
+	err = s.exec.Run(ctx, "Facets", func(ctx context.Context, querier internal.Querier) error {
+		rows, err := querier.QueryContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		facets, err = internal.ScanFacets(rows, s.schema, fields)
+		return err
+	})
+	return facets, err
+}
@@ -2,17 +2,15 @@ package pgsql
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log/slog"
-	"reflect"
-	"strconv"
 	"strings"
 	"time"
 
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
 	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 	"github.com/doug-martin/goqu/v9"
-	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/rs/rest-layer/resource"
 	"github.com/rs/rest-layer/schema"
@@ -22,28 +20,33 @@ import (
 	"github.com/doug-martin/goqu/v9/exp"
 )
 
-func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, error) {
-	builder := s.dialect.From(s.table)
-	buildSelects(q, builder)
-	buildWheres(s.schema, q, builder)
-	buildSorts(q, builder)
-	buildPagination(q, builder)
+func (s store) Find(ctx context.Context, q *query.Query) (list *resource.ItemList, err error) {
+	defer internal.Measure(s.opts, s.table, "Find", time.Now(), &err)
 
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	slog.DebugContext(ctx, "pgsql.Find", "sql", sqlStr, "args", args)
-
-	rows, err := s.db.QueryContext(ctx, sqlStr, args...)
-	if err != nil {
-		return nil, errors.Wrap(err, fmt.Sprintf("sql: %s args: %v", sqlStr, args))
+	builder := s.dialect.From(source)
+	jsonColumns, arrayColumns := s.buildSelects(q, builder)
+	if pgsql.EmbedsReferences(ctx) {
+		internal.Embed(builder, s.table, s.schema, q.Projection, s.opts.EmbeddedReferences, func(name string) exp.Expression {
+			return goqu.I(s.table + "." + name)
+		})
 	}
-	defer rows.Close()
+	if err := buildWheres(s.schema, s.opts, q, builder); err != nil {
+		return nil, errors.Wrapf(err, "predicate: %v", q.Predicate)
+	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
+	if err := buildSorts(s.schema, q, builder); err != nil {
+		return nil, err
+	}
+	buildPagination(q, builder)
 
-	// result mapping
-	cols, err := rows.Columns()
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +61,42 @@ func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, er
 		Limit: limit,
 		Items: []*resource.Item{},
 	}
+
+	err = s.exec.Run(ctx, "Find", func(ctx context.Context, querier internal.Querier) error {
+		rows, err := querier.QueryContext(ctx, sqlStr, args...)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("sql: %s args: %v", sqlStr, args))
+		}
+		defer rows.Close()
+
+		err = s.scanItems(rows, jsonColumns, arrayColumns, func(item *resource.Item) error {
+			result.Items = append(result.Items, item)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s store) FindEmbedded(ctx context.Context, q *query.Query) (*resource.ItemList, error) {
+	return s.Find(pgsql.EmbedReferences(ctx), q)
+}
+
+// scanItems maps every row to a resource.Item and hands it to fn, decoding
+// the values of jsonColumns and arrayColumns.
+func (s store) scanItems(rows *sql.Rows, jsonColumns, arrayColumns schema.Fields, fn func(item *resource.Item) error) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
 	for rows.Next() {
 		rowMap := make(map[string]any)
 		rowVals := make([]any, len(cols))
@@ -71,17 +110,27 @@ func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, er
 
 		err := rows.Scan(rowValPtrs...)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
+		embedded := make(map[string]any)
+
 		for i, v := range rowVals {
+			if name, ok := strings.CutPrefix(cols[i], internal.EmbeddedPrefix); ok {
+				if b, ok := v.([]byte); ok {
+					v = string(b)
+				}
+				embedded[name] = v
+				continue
+			}
+
 			// Skip null values
 			if v == nil {
 				continue
 			}
 
 			b, ok := v.([]byte)
-			if ok {
+			if ok && !internal.Binary(s.schema.Fields[cols[i]]) {
 				v = string(b)
 			}
 
@@ -89,27 +138,44 @@ func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, er
 			case "_etag":
 				etag = v.(string)
 			case "_updated":
-				updated = v.(time.Time)
-			default:
+				updated = internal.UTC(s.opts, v).(time.Time)
+			case pgsql.SearchColumn:
+				continue
+			case pgsql.DeletedColumn:
+				if s.opts.SoftDelete {
+					continue
+				}
 				rowMap[cols[i]] = v
+			default:
+				rowMap[cols[i]] = internal.UTC(s.opts, v)
 			}
 		}
 
-		// Converting itemID from int64 to int
-		itemID := rowMap["id"]
-		switch t := itemID.(type) {
-		case int64:
-			itemID = strconv.Itoa(int(t))
+		itemID, err := internal.IDs(s.schema, s.opts).ParseID(rowMap["id"])
+		if err != nil {
+			return err
 		}
 
 		// Converting json string to json node
-		for name, field := range s.jsonFields {
+		for name, field := range jsonColumns {
 			if c, ok := rowMap[name]; ok {
 				if jsonStr, ok := c.(string); ok {
 					rowMap[name] = toJsonNode(&field, jsonStr)
 				}
 			}
 		}
+		for name, field := range arrayColumns {
+			if c, ok := rowMap[name].(string); ok {
+				values, err := internal.ParseNativeArray(field.Validator.(*schema.Array), c)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				rowMap[name] = values
+			}
+		}
+		if err := internal.MergeEmbedded(rowMap, embedded); err != nil {
+			return err
+		}
 
 		item := &resource.Item{
 			ID:      itemID,
@@ -118,27 +184,40 @@ func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, er
 			Payload: rowMap,
 		}
 		internal.FixSchemaTypes(s.schema, item.Payload)
-		result.Items = append(result.Items, item)
+
+		if err := fn(item); err != nil {
+			return err
+		}
 	}
 
-	return result, nil
+	return nil
 }
 
-func (s store) Count(ctx context.Context, q *query.Query) (int, error) {
-	builder := s.dialect.From(s.table).Select(goqu.COUNT(goqu.Star()))
-	buildWheres(s.schema, q, builder)
+func (s store) Count(ctx context.Context, q *query.Query) (total int, err error) {
+	defer internal.Measure(s.opts, s.table, "Count", time.Now(), &err)
 
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
 	if err != nil {
 		return 0, err
 	}
+	builder := s.dialect.From(source).Select(goqu.COUNT(goqu.Star()))
+	if err := buildWheres(s.schema, s.opts, q, builder); err != nil {
+		return 0, err
+	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
 
-	slog.DebugContext(ctx, "pgsql.Count", "sql", sqlStr, "args", args)
-
-	row := s.db.QueryRowContext(ctx, sqlStr, args...)
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		return 0, err
+	}
 
 	var count int
-	err = row.Scan(&count)
+	err = s.exec.Run(ctx, "Count", func(ctx context.Context, querier internal.Querier) error {
+		return querier.QueryRowContext(ctx, sqlStr, args...).Scan(&count)
+	})
 
 	return count, err
 }
@@ -157,8 +236,14 @@ func toJsonNode(field *schema.Field, cell string) any {
 			return nil
 		}
 		return jsonNode
+	default:
+		// A projected sub-field may hold any value
+		var jsonNode any
+		if err := json.Unmarshal([]byte(cell), &jsonNode); err != nil {
+			return nil
+		}
+		return jsonNode
 	}
-	return nil
 }
 
 func buildPagination(q *query.Query, builder *goqu.SelectDataset) {
@@ -174,38 +259,109 @@ func buildPagination(q *query.Query, builder *goqu.SelectDataset) {
 	*builder = *builder.Offset(uint(offset))
 }
 
-func buildSorts(q *query.Query, builder *goqu.SelectDataset) {
+// buildSorts orders builder by the sort of q.
+func buildSorts(s *schema.Schema, q *query.Query, builder *goqu.SelectDataset) error {
 	for _, field := range q.Sort {
+		if search, ok := internal.SearchValidator(s, field.Name); ok {
+			if rank, ok := internal.SearchRank(search, field.Name, q.Predicate); ok {
+				if field.Reversed {
+					*builder = *builder.OrderAppend(rank.Desc())
+				} else {
+					*builder = *builder.OrderAppend(rank.Asc())
+				}
+			}
+			continue
+		}
+		if internal.PointField(s, field.Name) {
+			distance, err := internal.GeoDistance(field.Name, q.Predicate, pointField("", field.Name))
+			if err != nil {
+				return err
+			}
+			if field.Reversed {
+				*builder = *builder.OrderAppend(distance.Desc())
+			} else {
+				*builder = *builder.OrderAppend(distance.Asc())
+			}
+			continue
+		}
+
 		if field.Reversed {
-			*builder = *builder.Order(goqu.C(field.Name).Desc())
+			*builder = *builder.OrderAppend(goqu.C(field.Name).Desc())
 		} else {
-			*builder = *builder.Order(goqu.C(field.Name).Asc())
+			*builder = *builder.OrderAppend(goqu.C(field.Name).Asc())
 		}
 	}
+	return nil
 }
 
-func buildWheres(s *schema.Schema, q *query.Query, builder *goqu.SelectDataset) {
-	expressions := predicteToExpressions(s, q.Predicate)
+func buildWheres(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *goqu.SelectDataset) error {
+	expressions, err := predicteToExpressions("", s, opts, q.Predicate)
+	if err != nil {
+		return err
+	}
 	*builder = *builder.Where(expressions...)
+	return nil
 }
 
-func buildSelects(q *query.Query, builder *goqu.SelectDataset) {
+// buildSelects selects the projected columns, returning the JSON and native
+// array columns of the result with their fields.
+func (s store) buildSelects(q *query.Query, builder *goqu.SelectDataset) (jsonColumns, arrayColumns schema.Fields) {
 	pj := q.Projection
 	if len(pj) == 0 || hasStar(pj) {
 		*builder = *builder.Select(goqu.Star())
-		return
+		return s.jsonFields, s.arrayFields
 	}
 
-	selectFields := make([]any, 0, len(pj))
-	for _, field := range pj {
-		if len(field.Alias) > 0 {
-			selectFields = append(selectFields, goqu.I(field.Name).As(field.Alias), goqu.I(field.Name))
-		} else {
-			selectFields = append(selectFields, goqu.I(field.Name))
+	jsonColumns = schema.Fields{}
+	arrayColumns = schema.Fields{}
+	selectFields := []any{goqu.I("id"), goqu.I("_etag"), goqu.I("_updated")}
+	for _, field := range internal.ProjectedFields(pj) {
+		column := field.Path[0]
+		if field.Key == column && column == "id" {
+			continue
+		}
+
+		jsonField, isJSON := s.jsonFields[column]
+		switch {
+		case !isJSON:
+			if arrayField, ok := s.arrayFields[column]; ok {
+				arrayColumns[field.Key] = arrayField
+			}
+			// Scalar and native array columns have no sub-fields to project
+			if field.Key == column {
+				selectFields = append(selectFields, goqu.I(column))
+				continue
+			}
+			selectFields = append(selectFields, goqu.I(column).As(exp.NewIdentifierExpression("", "", field.Key)))
+			continue
+		case len(field.Path) > 1:
+			jsonColumns[field.Key] = schema.Field{}
+			if f := s.schema.GetField(strings.Join(field.Path, ".")); f != nil {
+				jsonColumns[field.Key] = *f
+			}
+		default:
+			jsonColumns[field.Key] = jsonField
+			if field.Key == column && field.Fields == nil {
+				selectFields = append(selectFields, goqu.I(column))
+				continue
+			}
 		}
+		selectFields = append(selectFields, goqu.L("?", internal.ProjectedValue(field, columnPath)).As(exp.NewIdentifierExpression("", "", field.Key)))
 	}
 
 	*builder = *builder.Select(selectFields...)
+	return jsonColumns, arrayColumns
+}
+
+// columnPath returns the JSONB value at path, within the column named by its
+// first element.
+func columnPath(path []string) exp.Expression {
+	args := make([]any, len(path))
+	args[0] = goqu.I(path[0])
+	for i, name := range path[1:] {
+		args[i+1] = name
+	}
+	return goqu.L("?"+strings.Repeat("->?", len(path)-1), args...)
 }
 
 func hasStar(pj query.Projection) bool {
@@ -223,73 +379,154 @@ func match(pj query.Projection, predicate func(pf query.ProjectionField) bool) b
 	return false
 }
 
-func predicteToExpressions(s *schema.Schema, q query.Predicate) (expressions []goqu.Expression) {
+// predicteToExpressions returns the expressions of q, on the fields of s
+// within parent.
+func predicteToExpressions(parent string, s *schema.Schema, opts *pgsql.Options, q query.Predicate) (expressions []goqu.Expression, err error) {
 	for _, e := range q {
+		if expr, ok := internal.GeoPredicate(e, func(field string) exp.Expression {
+			return pointField(parent, field)
+		}); ok {
+			expressions = append(expressions, expr)
+			continue
+		}
+
+		if field, ok := internal.ArrayPredicateField(e); ok && parent == "" && s != nil && nativeArray(s.Fields[field], opts) {
+			expr, ok, err := internal.NativeArrayPredicate(e, goqu.C(field))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				expressions = append(expressions, expr)
+				continue
+			}
+		}
+
+		expr, ok, err := internal.ArrayPredicate(s, e, func(field string) exp.Expression {
+			return postgresJsonbSupport(parent, field, true)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			expressions = append(expressions, expr)
+			continue
+		}
+
 		switch t := e.(type) {
 		case *query.And:
+			var exprs []exp.Expression
 			for _, subExp := range *t {
-				expressions = append(expressions, goqu.And(predicteToExpressions(s, query.Predicate{subExp})...))
+				var sube []exp.Expression
+				sube, err = predicteToExpressions(parent, s, opts, query.Predicate{subExp})
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, sube...)
 			}
+			expressions = append(expressions, goqu.And(exprs...))
+
 		case *query.Or:
+			var exprs []exp.Expression
 			for _, subExp := range *t {
-				expressions = append(expressions, goqu.Or(predicteToExpressions(s, query.Predicate{subExp})...))
+				var sube []exp.Expression
+				sube, err = predicteToExpressions(parent, s, opts, query.Predicate{subExp})
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, sube...)
 			}
+			expressions = append(expressions, goqu.Or(exprs...))
+
 		case *query.In:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).In(t.Values))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).In(t.Values))
+
 		case *query.NotIn:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).NotIn(t.Values))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).NotIn(t.Values))
 
 		case *query.Equal:
-			isSchemaArray := false
-			ff := s.GetField(t.Field)
-			if ff != nil {
-				if _, ok := ff.Validator.(*schema.Array); ok {
-					isSchemaArray = true
-				}
-			}
-			var expr exp.Expression
-			if isSchemaArray {
-				expr = goqu.L("? \\?\\| ?", postgresJsonbSupport(t.Field, false), pq.Array(convertToArray(t.Value)))
-			} else {
-				expr = postgresJsonbSupport(t.Field, false).Eq(t.Value)
+			if search, ok := internal.SearchValidator(s, t.Field); ok && parent == "" {
+				expressions = append(expressions, internal.SearchMatch(search, t.Value))
+				continue
 			}
-			expressions = append(expressions, expr)
 
-		// case *query.Equal:
-		// 	expressions = append(expressions, goqu.C(postgresJsonbSupport(t.Field)).Eq(t.Value))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).Eq(t.Value))
+
 		case *query.NotEqual:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).Neq(t.Value))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).Neq(t.Value))
+
 		case *query.GreaterThan:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).Gt(t.Value))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).Gt(t.Value))
+
 		case *query.GreaterOrEqual:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).Gte(t.Value))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).Gte(t.Value))
+
 		case *query.LowerThan:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).Lt(t.Value))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).Lt(t.Value))
+
 		case *query.LowerOrEqual:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).Lte(t.Value))
+			expressions = append(expressions, typedField(parent, s, t.Field, opts).Lte(t.Value))
+
 		case *query.Regex:
-			expressions = append(expressions, postgresJsonbSupport(t.Field, false).RegexpLike(t.Value))
+			pattern, caseInsensitive, err := internal.TranslateRegex(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			field := postgresJsonbSupport(parent, t.Field, false)
+			switch {
+			case t.Negated && caseInsensitive:
+				expressions = append(expressions, field.RegexpNotILike(pattern))
+			case t.Negated:
+				expressions = append(expressions, field.RegexpNotLike(pattern))
+			case caseInsensitive:
+				expressions = append(expressions, field.RegexpILike(pattern))
+			default:
+				expressions = append(expressions, field.RegexpLike(pattern))
+			}
+
 		case *query.Exist:
-			expr := postgresJsonbSupport(t.Field, false).IsNotNull()
-			expressions = append(expressions, expr)
+			expressions = append(expressions, postgresJsonbSupport(parent, t.Field, false).IsNotNull())
+
 		case *query.NotExist:
-			expr := postgresJsonbSupport(t.Field, false).IsNull()
-			expressions = append(expressions, expr)
+			expressions = append(expressions, postgresJsonbSupport(parent, t.Field, false).IsNull())
+
+		case *query.ElemMatch:
+			elemSchema, err := elementSchema(s, t.Field)
+			if err != nil {
+				return nil, err
+			}
+			alias := elementAlias(parent, t.Field)
+			exprs := make([]exp.Expression, 0, len(t.Exps))
+			for _, p := range t.Exps {
+				var sube []exp.Expression
+				sube, err = predicteToExpressions(alias, elemSchema, opts, query.Predicate{p})
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, goqu.And(sube...))
+			}
+			elements := goqu.Dialect("postgres").
+				From(goqu.L("jsonb_array_elements(?) AS ?(?)", postgresJsonbSupport(parent, t.Field, true), goqu.I(alias), goqu.I(alias))).
+				Select(goqu.L("1")).
+				Where(exprs...)
+			expressions = append(expressions, goqu.L("EXISTS ?", elements))
+
 		default:
-			fmt.Printf("%T\n", t)
-			slog.Warn("not supported predicate. ignored")
+			return nil, fmt.Errorf("unsupported predicate %T", t)
 		}
 	}
 	return
 }
 
-func postgresJsonbSupport(field string, asJSOBN bool) exp.LiteralExpression {
-	if !strings.Contains(field, ".") {
-		return goqu.L("?", goqu.C(field))
+// postgresJsonbSupport returns the expression of field: a column, or a path
+// in the JSONB column of an object. Within an $elemMatch, parent is the alias
+// of the array element.
+func postgresJsonbSupport(parent, field string, asJSOBN bool) exp.LiteralExpression {
+	if parent != "" {
+		field = parent + "." + field
 	}
 
-	if field == "id" {
-		return goqu.L(field)
+	if !strings.Contains(field, ".") {
+		return goqu.L("?", goqu.C(field))
 	}
 
 	var exprs []any
@@ -317,16 +554,54 @@ func postgresJsonbSupport(field string, asJSOBN bool) exp.LiteralExpression {
 	return goqu.L(literalText, exprs...)
 }
 
-func convertToArray(input interface{}) interface{} {
-	// Check if input is array using reflect
-	if reflect.TypeOf(input).Kind() == reflect.Array || reflect.TypeOf(input).Kind() == reflect.Slice {
-		return input
+// pointField returns the point expression of field: a point column, or the
+// point of a lat/lng object in a JSONB column. Within an $elemMatch, parent
+// is the alias of the array element.
+func pointField(parent, field string) exp.Expression {
+	if parent != "" {
+		return goqu.L(internal.JSONPoint(parent, field))
 	}
+	return goqu.L(pointText(field))
+}
 
-	// Create an array of the input type
-	arrayType := reflect.SliceOf(reflect.TypeOf(input))
-	array := reflect.MakeSlice(arrayType, 1, 1)
-	array.Index(0).Set(reflect.ValueOf(input))
+// fieldExpression is the comparable expression of a field.
+type fieldExpression interface {
+	exp.Comparable
+	exp.Inable
+}
 
-	return array.Interface()
+// typedField returns the expression of field, cast to the type of its schema
+// field when it is read as text from a JSONB column.
+func typedField(parent string, s *schema.Schema, field string, opts *pgsql.Options) fieldExpression {
+	expr := postgresJsonbSupport(parent, field, false)
+	if parent == "" && !strings.Contains(field, ".") {
+		return expr
+	}
+	if pgType := internal.CastType(s, field, opts); pgType != "" {
+		return goqu.Cast(expr, pgType)
+	}
+	return expr
+}
+
+// elementSchema returns the schema of the objects of the array field.
+func elementSchema(s *schema.Schema, field string) (*schema.Schema, error) {
+	if s != nil {
+		if f := s.GetField(field); f != nil {
+			if array, ok := f.Validator.(*schema.Array); ok {
+				if object, ok := array.Values.Validator.(*schema.Object); ok {
+					return object.Schema, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("$elemMatch on %s: not an array of objects", field)
+}
+
+// elementAlias returns the alias of the elements of the array field.
+func elementAlias(parent, field string) string {
+	alias := strings.ReplaceAll(field, ".", "_")
+	if parent != "" {
+		alias = parent + "_" + alias
+	}
+	return alias
 }
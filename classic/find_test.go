@@ -0,0 +1,517 @@
+package pgsql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/pgtypes"
+)
+
+type Decimal struct {
+	schema.String
+}
+
+func (s Decimal) PostgresType() string {
+	return "NUMERIC"
+}
+
+var itemSchema = schema.Schema{
+	Fields: schema.Fields{
+		"name":   {Validator: &schema.String{}},
+		"age":    {Validator: &schema.Integer{}},
+		"weight": {Validator: &Decimal{}},
+		"tags": {
+			Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.String{}},
+			},
+		},
+		"address": {
+			Validator: &schema.Object{Schema: &schema.Schema{
+				Fields: schema.Fields{
+					"city":  {Validator: &schema.String{}},
+					"zip":   {Validator: &schema.Integer{}},
+					"since": {Validator: &schema.Time{}},
+				},
+			}},
+		},
+		"assets": {
+			Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.Object{Schema: &schema.Schema{
+					Fields: schema.Fields{
+						"kind":  {Validator: &schema.String{}},
+						"value": {Validator: &schema.Float{}},
+					},
+				}}},
+			},
+		},
+		"q": pgsql.SearchField,
+	},
+}
+
+func Test_buildWheres(t *testing.T) {
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		predicate  query.Predicate
+		wantSQL    string
+		wantArgs   []any
+		wantErrStr string
+	}{
+		{
+			name:      "query.Equal",
+			predicate: query.Predicate{&query.Equal{Field: "name", Value: "John"}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("name" = $1)`,
+			wantArgs:  []any{"John"},
+		},
+		{
+			name:      "query.Equal: nested, typed",
+			predicate: query.Predicate{&query.Equal{Field: "address.zip", Value: 1000}},
+			wantSQL:   `SELECT * FROM "table" WHERE (CAST("address"->>$1 AS INTEGER) = $2)`,
+			wantArgs:  []any{"zip", int64(1000)},
+		},
+		{
+			name:      "query.Equal: nested string",
+			predicate: query.Predicate{&query.Equal{Field: "address.city", Value: "Sofia"}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("address"->>$1 = $2)`,
+			wantArgs:  []any{"city", "Sofia"},
+		},
+		{
+			name:      "query.Equal: array",
+			predicate: query.Predicate{&query.Equal{Field: "tags", Value: "red"}},
+			wantSQL:   `SELECT * FROM "table" WHERE "tags" @> $1::jsonb`,
+			wantArgs:  []any{`["red"]`},
+		},
+		{
+			name:      "query.NotEqual: array",
+			predicate: query.Predicate{&query.NotEqual{Field: "tags", Value: "red"}},
+			wantSQL:   `SELECT * FROM "table" WHERE NOT coalesce("tags" @> $1::jsonb, false)`,
+			wantArgs:  []any{`["red"]`},
+		},
+		{
+			name:      "query.In: array",
+			predicate: query.Predicate{&query.In{Field: "tags", Values: []query.Value{"red", []any{"green", "blue"}}}},
+			wantSQL:   `SELECT * FROM "table" WHERE "tags" @> ANY($1::jsonb[])`,
+			wantArgs:  []any{`{"[\"red\"]","[\"green\",\"blue\"]"}`},
+		},
+		{
+			name:      "pgsql.Size",
+			predicate: query.Predicate{&pgsql.Size{Field: "tags", Op: pgsql.SizeEqual, Value: 0}},
+			wantSQL:   `SELECT * FROM "table" WHERE jsonb_array_length(CASE WHEN jsonb_typeof("tags") = 'array' THEN "tags" END) = $1`,
+			wantArgs:  []any{int64(0)},
+		},
+		{
+			name:      "query.Equal: search",
+			predicate: query.Predicate{&query.Equal{Field: "q", Value: "apple pie"}},
+			wantSQL:   `SELECT * FROM "table" WHERE "search_vector" @@ websearch_to_tsquery($1::regconfig, $2)`,
+			wantArgs:  []any{"simple", "apple pie"},
+		},
+		{
+			name:      "query.GreaterThan: nested time",
+			predicate: query.Predicate{&query.GreaterThan{Field: "address.since", Value: since}},
+			wantSQL:   `SELECT * FROM "table" WHERE (CAST("address"->>$1 AS TIMESTAMP) > $2)`,
+			wantArgs:  []any{"since", since},
+		},
+		{
+			name:      "query.LowerOrEqual",
+			predicate: query.Predicate{&query.LowerOrEqual{Field: "age", Value: 30}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("age" <= $1)`,
+			wantArgs:  []any{int64(30)},
+		},
+		{
+			name:      "query.In: nested, typed",
+			predicate: query.Predicate{&query.In{Field: "address.zip", Values: []query.Value{1000, 2000}}},
+			wantSQL:   `SELECT * FROM "table" WHERE (CAST("address"->>$1 AS INTEGER) IN ($2, $3))`,
+			wantArgs:  []any{"zip", int64(1000), int64(2000)},
+		},
+		{
+			name:      "query.NotIn",
+			predicate: query.Predicate{&query.NotIn{Field: "name", Values: []query.Value{"John", "Jane"}}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("name" NOT IN ($1, $2))`,
+			wantArgs:  []any{"John", "Jane"},
+		},
+		{
+			name: "query.Or",
+			predicate: query.Predicate{&query.Or{
+				&query.Equal{Field: "name", Value: "John"},
+				&query.GreaterThan{Field: "age", Value: 30},
+			}},
+			wantSQL:  `SELECT * FROM "table" WHERE (("name" = $1) OR ("age" > $2))`,
+			wantArgs: []any{"John", int64(30)},
+		},
+		{
+			name: "query.And",
+			predicate: query.Predicate{&query.And{
+				&query.Equal{Field: "name", Value: "John"},
+				&query.GreaterThan{Field: "age", Value: 30},
+			}},
+			wantSQL:  `SELECT * FROM "table" WHERE (("name" = $1) AND ("age" > $2))`,
+			wantArgs: []any{"John", int64(30)},
+		},
+		{
+			name:      "query.Regex: case insensitive, negated",
+			predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("(?i)^jo"), Negated: true}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("name" !~* $1)`,
+			wantArgs:  []any{"^jo"},
+		},
+		{
+			name:      "query.Exist: nested",
+			predicate: query.Predicate{&query.Exist{Field: "address.city"}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("address"->>$1 IS NOT NULL)`,
+			wantArgs:  []any{"city"},
+		},
+		{
+			name:      "query.NotExist",
+			predicate: query.Predicate{&query.NotExist{Field: "name"}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("name" IS NULL)`,
+			wantArgs:  []any{},
+		},
+		{
+			name: "query.ElemMatch",
+			predicate: query.Predicate{&query.ElemMatch{Field: "assets", Exps: []query.Expression{
+				&query.Equal{Field: "kind", Value: "car"},
+				&query.GreaterThan{Field: "value", Value: 1000.5},
+			}}},
+			wantSQL: `SELECT * FROM "table" WHERE EXISTS (SELECT 1 FROM jsonb_array_elements("assets") AS "assets"("assets") ` +
+				`WHERE (("assets"->>$1 = $2) AND (CAST("assets"->>$3 AS DOUBLE PRECISION) > $4)))`,
+			wantArgs: []any{"kind", "car", "value", 1000.5},
+		},
+		{
+			name: "query.ElemMatch: not an array of objects",
+			predicate: query.Predicate{&query.ElemMatch{Field: "tags", Exps: []query.Expression{
+				&query.Equal{Field: "kind", Value: "car"},
+			}}},
+			wantErrStr: "$elemMatch on tags: not an array of objects",
+		},
+		{
+			name:       "query.Regex: unsupported repetition",
+			predicate:  query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("a{300}")}},
+			wantErrStr: "regex a{300}: repetition count exceeds the PostgreSQL limit of 255",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := goqu.Dialect("postgres").From("table")
+			err := buildWheres(&itemSchema, &pgsql.Options{}, &query.Query{Predicate: tt.predicate}, builder)
+			if tt.wantErrStr != "" {
+				if err == nil || err.Error() != tt.wantErrStr {
+					t.Fatalf("Error = %v, want %s", err, tt.wantErrStr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			sql, args, err := builder.Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			for i, arg := range args {
+				if v, ok := arg.(interface{ Value() (driver.Value, error) }); ok {
+					args[i], _ = v.Value()
+				}
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_buildSorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    query.Query
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name: "query.Sort",
+			query: query.Query{
+				Sort: []query.SortField{
+					{Name: "name", Reversed: true},
+					{Name: "age"},
+				},
+			},
+			wantSQL:  `SELECT * FROM "table" ORDER BY "name" DESC, "age" ASC`,
+			wantArgs: []any{},
+		},
+		{
+			name: "query.Sort: search rank",
+			query: query.Query{
+				Predicate: query.Predicate{&query.Equal{Field: "q", Value: "apple pie"}},
+				Sort:      []query.SortField{{Name: "q", Reversed: true}},
+			},
+			wantSQL:  `SELECT * FROM "table" ORDER BY ts_rank("search_vector", websearch_to_tsquery($1::regconfig, $2)) DESC`,
+			wantArgs: []any{"simple", "apple pie"},
+		},
+		{
+			name: "query.Sort: search without query",
+			query: query.Query{
+				Sort: []query.SortField{{Name: "q"}},
+			},
+			wantSQL:  `SELECT * FROM "table"`,
+			wantArgs: []any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := goqu.Dialect("postgres").From("table")
+			if err := buildSorts(&itemSchema, &tt.query, builder); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			sql, args, err := builder.Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_buildSelects(t *testing.T) {
+	s := store{schema: &itemSchema, jsonFields: getJsonFields(itemSchema.Fields)}
+	native := store{schema: &itemSchema}
+	native.jsonFields, native.arrayFields = getColumnFields(itemSchema.Fields, &pgsql.Options{NativeArrays: true})
+
+	tests := []struct {
+		name             string
+		store            store
+		projection       query.Projection
+		wantSQL          string
+		wantArgs         []any
+		wantJSONColumns  []string
+		wantArrayColumns []string
+	}{
+		{
+			name:            "all",
+			wantSQL:         `SELECT * FROM "table"`,
+			wantJSONColumns: []string{"address", "assets", "tags"},
+		},
+		{
+			name:            "columns",
+			projection:      query.Projection{{Name: "id"}, {Name: "name"}, {Name: "tags"}},
+			wantSQL:         `SELECT "id", "_etag", "_updated", "name", "tags" FROM "table"`,
+			wantJSONColumns: []string{"tags"},
+		},
+		{
+			name:            "alias",
+			projection:      query.Projection{{Name: "name", Alias: "n"}, {Name: "name"}, {Name: "tags", Alias: "t"}},
+			wantSQL:         `SELECT "id", "_etag", "_updated", "name", "tags" FROM "table"`,
+			wantJSONColumns: []string{"tags"},
+		},
+		{
+			name:            "nested",
+			projection:      query.Projection{{Name: "address.city"}, {Name: "address.zip", Alias: "zip"}},
+			wantSQL:         `SELECT "id", "_etag", "_updated", CASE WHEN jsonb_typeof("address") = 'object' THEN jsonb_build_object('city', "address"->$1) ELSE "address" END AS "address", "address"->$2 AS "address.zip" FROM "table"`,
+			wantArgs:        []any{"city", "zip"},
+			wantJSONColumns: []string{"address", "address.zip"},
+		},
+		{
+			name:             "native arrays",
+			store:            native,
+			wantSQL:          `SELECT * FROM "table"`,
+			wantJSONColumns:  []string{"address", "assets"},
+			wantArrayColumns: []string{"tags"},
+		},
+		{
+			name:             "native arrays: alias",
+			store:            native,
+			projection:       query.Projection{{Name: "tags", Alias: "t"}},
+			wantSQL:          `SELECT "id", "_etag", "_updated", "tags" FROM "table"`,
+			wantArrayColumns: []string{"tags"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := goqu.Dialect("postgres").From("table")
+			s := s
+			if tt.store.schema != nil {
+				s = tt.store
+			}
+			jsonColumns, arrayColumns := s.buildSelects(&query.Query{Projection: tt.projection}, builder)
+			sql, args, err := builder.Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if len(args) > 0 || len(tt.wantArgs) > 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+				}
+			}
+			for _, name := range tt.wantJSONColumns {
+				if _, ok := jsonColumns[name]; !ok {
+					t.Errorf("JSON column %s not decoded", name)
+				}
+			}
+			if len(jsonColumns) != len(tt.wantJSONColumns) {
+				t.Errorf("JSON columns = %v, want %v", jsonColumns, tt.wantJSONColumns)
+			}
+			for _, name := range tt.wantArrayColumns {
+				if _, ok := arrayColumns[name]; !ok {
+					t.Errorf("Array column %s not decoded", name)
+				}
+			}
+			if len(arrayColumns) != len(tt.wantArrayColumns) {
+				t.Errorf("Array columns = %v, want %v", arrayColumns, tt.wantArrayColumns)
+			}
+		})
+	}
+}
+
+func Test_buildWheres_nativeArrays(t *testing.T) {
+	opts := &pgsql.Options{NativeArrays: true}
+
+	tests := []struct {
+		name      string
+		predicate query.Predicate
+		wantSQL   string
+		wantArgs  []any
+	}{
+		{
+			name:      "query.Equal",
+			predicate: query.Predicate{&query.Equal{Field: "tags", Value: "red"}},
+			wantSQL:   `SELECT * FROM "table" WHERE $1 = ANY("tags")`,
+			wantArgs:  []any{"red"},
+		},
+		{
+			name:      "query.Equal: array",
+			predicate: query.Predicate{&query.Equal{Field: "tags", Value: []any{"red", "blue"}}},
+			wantSQL:   `SELECT * FROM "table" WHERE "tags" @> $1`,
+			wantArgs:  []any{`{"red","blue"}`},
+		},
+		{
+			name:      "query.NotEqual",
+			predicate: query.Predicate{&query.NotEqual{Field: "tags", Value: "red"}},
+			wantSQL:   `SELECT * FROM "table" WHERE NOT coalesce($1 = ANY("tags"), false)`,
+			wantArgs:  []any{"red"},
+		},
+		{
+			name:      "query.In",
+			predicate: query.Predicate{&query.In{Field: "tags", Values: []query.Value{"red", []any{"green", "blue"}}}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("tags" && $1 OR "tags" @> $2)`,
+			wantArgs:  []any{`{"red"}`, `{"green","blue"}`},
+		},
+		{
+			name:      "pgsql.ContainsAny",
+			predicate: query.Predicate{&pgsql.ContainsAny{Field: "tags", Values: []any{"red", "blue"}}},
+			wantSQL:   `SELECT * FROM "table" WHERE "tags" && $1`,
+			wantArgs:  []any{`{"red","blue"}`},
+		},
+		{
+			name:      "pgsql.Size",
+			predicate: query.Predicate{&pgsql.Size{Field: "tags", Op: pgsql.SizeGreaterThan, Value: 1}},
+			wantSQL:   `SELECT * FROM "table" WHERE cardinality("tags") > $1`,
+			wantArgs:  []any{int64(1)},
+		},
+		{
+			name:      "query.Equal: JSONB array",
+			predicate: query.Predicate{&query.Equal{Field: "assets", Value: map[string]any{"kind": "car"}}},
+			wantSQL:   `SELECT * FROM "table" WHERE "assets" @> $1::jsonb`,
+			wantArgs:  []any{`[{"kind":"car"}]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := goqu.Dialect("postgres").From("table")
+			if err := buildWheres(&itemSchema, opts, &query.Query{Predicate: tt.predicate}, builder); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			sql, args, err := builder.Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			for i, arg := range args {
+				if v, ok := arg.(driver.Valuer); ok {
+					args[i], _ = v.Value()
+				}
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_buildCreateTable_nativeArrays(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"tags":   {Validator: &schema.Array{Values: schema.Field{Validator: &schema.String{}}}},
+			"scores": {Required: true, Validator: &schema.Array{Values: schema.Field{Validator: &schema.Integer{}}}},
+			"assets": itemSchema.Fields["assets"],
+		},
+	}
+	opts := &pgsql.Options{NativeArrays: true}
+	_, arrayFields := getColumnFields(sc.Fields, opts)
+
+	sql, _, err := buildCreateTable(sc, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{`"tags" TEXT[]`, `"scores" BIGINT[] NOT NULL`, `"assets" JSONB`} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL = %s, want %s", sql, want)
+		}
+	}
+
+	indexes := arrayIndexQueries("table", arrayFields)
+	want := []string{
+		`CREATE INDEX IF NOT EXISTS "table_scores_idx" ON "table" USING GIN ("scores")`,
+		`CREATE INDEX IF NOT EXISTS "table_tags_idx" ON "table" USING GIN ("tags")`,
+	}
+	if !reflect.DeepEqual(indexes, want) {
+		t.Errorf("Indexes = %v, want %v", indexes, want)
+	}
+}
+
+func Test_buildCreateTable_pgtypes(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"price":   {Required: true, Validator: pgtypes.Money{}},
+			"ref":     {Validator: pgtypes.UUID{}},
+			"born":    {Validator: pgtypes.Date{}},
+			"timeout": {Validator: pgtypes.Interval{}},
+			"address": {Validator: pgtypes.Inet{}},
+			"email":   {Validator: &pgtypes.CIText{}},
+			"avatar":  {Validator: pgtypes.Bytea{}},
+		},
+	}
+
+	sql, _, err := buildCreateTable(sc, &pgsql.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{`"price" NUMERIC(19,2) NOT NULL`, `"ref" UUID`, `"born" DATE`, `"timeout" INTERVAL`, `"address" INET`, `"email" CITEXT`, `"avatar" BYTEA`} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL = %s, want %s", sql, want)
+		}
+	}
+}
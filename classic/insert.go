@@ -2,9 +2,7 @@ package pgsql
 
 import (
 	"context"
-	"database/sql"
-	"log/slog"
-	"reflect"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/rs/rest-layer/resource"
@@ -14,23 +12,28 @@ import (
 )
 
 func (s store) Insert(ctx context.Context, items []*resource.Item) (err error) {
-	for _, item := range items {
-		if err = s.insertOne(ctx, item); err != nil {
-			return
+	defer internal.Measure(s.opts, s.table, "Insert", time.Now(), &err)
+
+	return s.exec.RunWrite(ctx, "Insert", func(ctx context.Context, q internal.Querier) error {
+		for _, item := range items {
+			if err := s.insertOne(ctx, q, item); err != nil {
+				return err
+			}
+			if err := internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationInsert, nil, item); err != nil {
+				return err
+			}
 		}
-	}
-
-	return
+		return nil
+	})
 }
 
-func (s store) insertOne(ctx context.Context, item *resource.Item) error {
-	row := internal.CopyRow(item.Payload)
+func (s store) insertOne(ctx context.Context, q internal.Querier, item *resource.Item) error {
+	row := internal.CopyRow(internal.UTCPayload(s.opts, item.Payload))
 	row["_etag"] = item.ETag
 	row["_updated"] = item.Updated
 
-	useSerial := reflect.DeepEqual(s.schema.Fields["id"], pgsql.SerialID)
-
-	if useSerial {
+	ids := internal.IDs(s.schema, s.opts)
+	if ids.Generated() {
 		delete(row, "id")
 	}
 
@@ -38,10 +41,11 @@ func (s store) insertOne(ctx context.Context, item *resource.Item) error {
 	if err := toJsonString(s.jsonFields, row); err != nil {
 		return err
 	}
+	toNativeArrays(s.arrayFields, row)
 
 	builder := s.dialect.Insert(s.table)
 
-	if useSerial {
+	if ids.Generated() {
 		builder = builder.Returning(goqu.L("id"))
 	}
 
@@ -50,23 +54,18 @@ func (s store) insertOne(ctx context.Context, item *resource.Item) error {
 		return err
 	}
 
-	slog.DebugContext(ctx, "pgsql.Insert", "sql", sqlStr, "args", args)
-
-	tx := pgsql.TransactionFromContext(ctx)
-	var result *sql.Row
-	if tx != nil {
-		result = tx.QueryRowContext(ctx, sqlStr, args...)
-	} else {
-		result = s.db.QueryRowContext(ctx, sqlStr, args...)
-	}
-
+	result := q.QueryRowContext(ctx, sqlStr, args...)
 	if result.Err() != nil {
 		return result.Err()
 	}
 
-	if useSerial {
-		var id string
-		if err = result.Scan(&id); err != nil {
+	if ids.Generated() {
+		var value any
+		if err = result.Scan(&value); err != nil {
+			return err
+		}
+		id, err := ids.ParseID(value)
+		if err != nil {
 			return err
 		}
 		item.Payload["id"] = id
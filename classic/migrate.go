@@ -2,30 +2,69 @@ package pgsql
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"log/slog"
-	"reflect"
+	"sort"
 	"strings"
 
 	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+	"github.com/lib/pq"
 	"github.com/rotisserie/eris"
 	"github.com/rs/rest-layer/schema"
 )
 
 func (s store) Migrate(ctx context.Context, sc *schema.Schema) (err error) {
-	sqlQuery, sqlParams, err := buildCreateQuery(s.table, sc)
+	db, err := s.exec.Resolve(ctx)
 	if err != nil {
 		return err
 	}
 
-	slog.DebugContext(ctx, "psql.Migrate", "sql", sqlQuery, "args", sqlParams)
+	return s.migrate(ctx, db, sc)
+}
+
+func (s store) migrate(ctx context.Context, db *sql.DB, sc *schema.Schema) (err error) {
+	q := s.exec.Trace(db, "Migrate")
+	_, arrayFields := getColumnFields(sc.Fields, s.opts)
+	sqlQuery, sqlParams, err := buildCreateQuery(s.table, sc, s.opts)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range internal.ExtensionQueries(sc) {
+		if _, err = q.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	_, err = q.ExecContext(ctx, sqlQuery, sqlParams...)
+	if err != nil {
+		return err
+	}
+
+	rlsQueries, err := internal.RowSecurityQueries(s.table, s.opts.RowSecurity)
+	if err != nil {
+		return err
+	}
+	queries := append(internal.TimeZoneQueries(s.table, s.opts, timeColumns(sc)...), arrayIndexQueries(s.table, arrayFields)...)
+	queries = append(queries, internal.SoftDeleteQueries(s.table, s.opts)...)
+	queries = append(queries, internal.HistoryQueries(s.table, s.opts)...)
+	queries = append(queries, internal.SearchQueries(s.table, sc, searchText)...)
+	queries = append(queries, internal.PointIndexQueries(s.table, sc, pointText)...)
+	queries = append(queries, internal.NotifyTriggerQueries(s.table, "_etag", s.opts)...)
+	queries = append(queries, internal.OutboxQueries(s.opts)...)
+	queries = append(queries, rlsQueries...)
+	for _, query := range queries {
+		if _, err = q.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
 
-	_, err = s.db.ExecContext(ctx, sqlQuery, sqlParams...)
-	return err
+	return nil
 }
 
-func buildCreateQuery(tableName string, s *schema.Schema) (sqlQuery string, sqlParams []any, err error) {
-	schemaQuery, schemaParams, err := buildCreateTable(s)
+func buildCreateQuery(tableName string, s *schema.Schema, opts *pgsql.Options) (sqlQuery string, sqlParams []any, err error) {
+	schemaQuery, schemaParams, err := buildCreateTable(s, opts)
 	if err != nil {
 		return "", []any{}, err
 	}
@@ -36,17 +75,32 @@ func buildCreateQuery(tableName string, s *schema.Schema) (sqlQuery string, sqlP
 	return sqlQuery, sqlParams, nil
 }
 
-func buildCreateTable(s *schema.Schema) (sqlQuery string, sqlParams []any, err error) {
+func buildCreateTable(s *schema.Schema, opts *pgsql.Options) (sqlQuery string, sqlParams []any, err error) {
 	fieldStrings := make([]string, 0, len(s.Fields))
 
+	ids, configured := internal.ConfiguredIDs(s, opts)
 	for fieldName, field := range s.Fields {
-		if fieldName == "id" && reflect.DeepEqual(field, pgsql.SerialID) {
-			fieldStrings = append(fieldStrings, "id SERIAL")
+		if fieldName == "id" && configured {
+			fieldStrings = append(fieldStrings, "id "+ids.ColumnType())
+			continue
+		}
+
+		// The search field is backed by the search document
+		if _, ok := field.Validator.(*pgsql.Search); ok {
+			continue
+		}
+
+		if nativeArray(field, opts) {
+			pgType := internal.NativeArrayType(field.Validator.(*schema.Array), opts)
+			if field.Required {
+				pgType += " NOT NULL"
+			}
+			fieldStrings = append(fieldStrings, `"`+fieldName+`" `+pgType)
 			continue
 		}
 
 		fieldName = `"` + fieldName + `"`
-		pgType, err := schemaFieldValidatorToPGType(&field)
+		pgType, err := internal.ColumnType(&field, opts)
 		if err != nil {
 			return "", []any{}, eris.Wrapf(err, "failed to convert field %s to pg type", fieldName)
 		}
@@ -54,65 +108,61 @@ func buildCreateTable(s *schema.Schema) (sqlQuery string, sqlParams []any, err e
 		fieldStrings = append(fieldStrings, fieldName+" "+pgType)
 	}
 
-	fieldStrings = append(fieldStrings, "_updated TIMESTAMP NOT NULL")
+	fieldStrings = append(fieldStrings, "_updated "+internal.TimeType(opts)+" NOT NULL")
 	fieldStrings = append(fieldStrings, "_etag CHAR(32) NOT NULL")
 
 	return strings.Join(fieldStrings, ","), []any{}, nil
 }
 
-func schemaFieldValidatorToPGType(field *schema.Field) (string, error) {
-	pgType := ""
-	switch f := field.Validator.(type) {
-	case *schema.String:
-		if f.MaxLen > 0 {
-			pgType = fmt.Sprintf("VARCHAR(%d)", f.MaxLen)
-		} else {
-			pgType = "VARCHAR"
+// timeColumns returns the columns of s holding times, or arrays of times.
+func timeColumns(s *schema.Schema) []string {
+	columns := []string{"_updated"}
+	for name, field := range s.Fields {
+		validator := field.Validator
+		if array, ok := validator.(*schema.Array); ok {
+			validator = array.Values.Validator
 		}
-	case *schema.Integer:
-		pgType = getIntegerScale(f)
-	case *schema.Float:
-		pgType = "DOUBLE PRECISION"
-	case *schema.Bool:
-		pgType = "BOOLEAN"
-	case *schema.Time:
-		pgType = "TIMESTAMP"
-	case *schema.URL, *schema.IP, *schema.Password:
-		pgType = "VARCHAR"
-	case *schema.Reference:
-		field := f.GetField("id")
-		var err error
-		pgType, err = schemaFieldValidatorToPGType(field)
-		if err != nil {
-			// TODO: this is a hack to get around the fact that we don't have a way to get the type of a reference field
-			pgType = "VARCHAR"
+		if _, ok := validator.(*schema.Time); ok {
+			columns = append(columns, name)
 		}
-	case *schema.Object, *schema.Dict, *schema.Array:
-		pgType = "JSONB"
-	case pgsql.PostgresTyper:
-		pgType = f.PostgresType()
-	case nil:
-		return "", fmt.Errorf("validator required")
-	default:
-		return "", fmt.Errorf("unsupported field validator type: %+v", f)
 	}
+	sort.Strings(columns[1:])
+	return columns
+}
 
-	if field.Required {
-		pgType += " NOT NULL"
+// arrayIndexQueries returns the statements creating the GIN indexes of the
+// native array columns of table.
+func arrayIndexQueries(table string, arrayFields schema.Fields) []string {
+	names := make([]string, 0, len(arrayFields))
+	for name := range arrayFields {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return pgType, nil
+	queries := make([]string, len(names))
+	for i, name := range names {
+		queries[i] = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)",
+			pq.QuoteIdentifier(table+"_"+name+"_idx"), pq.QuoteIdentifier(table), pq.QuoteIdentifier(name))
+	}
+	return queries
 }
 
-func getIntegerScale(f *schema.Integer) string {
-	if f.Boundaries == nil {
-		return "INTEGER"
+// searchText returns the expression of the text at path: a column, or a path
+// in the JSONB column of an object.
+func searchText(path string) string {
+	column, nested, ok := strings.Cut(path, ".")
+	if !ok {
+		return pq.QuoteIdentifier(column)
 	}
-	if f.Boundaries.Max == 0 || f.Boundaries.Max > 1<<31-1 {
-		return "BIGINT"
-	}
-	if f.Boundaries.Max > 1<<15-1 {
-		return "INTEGER"
+	return internal.JSONText(column, nested)
+}
+
+// pointText returns the expression of the point at path: a point column or
+// the point of a lat/lng object in a JSONB column.
+func pointText(path string) string {
+	column, nested, ok := strings.Cut(path, ".")
+	if !ok {
+		return pq.QuoteIdentifier(column)
 	}
-	return "SMALLINT"
+	return internal.JSONPoint(column, nested)
 }
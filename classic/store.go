@@ -7,6 +7,9 @@ import (
 	"github.com/doug-martin/goqu/v9"
 	"github.com/rs/rest-layer/resource"
 	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
 type PostgresStorer interface {
@@ -16,24 +19,74 @@ type PostgresStorer interface {
 
 type store struct {
 	table      string
-	db         *sql.DB
 	dialect    goqu.DialectWrapper
 	schema     *schema.Schema
 	jsonFields schema.Fields
+	// arrayFields are the fields stored in native array columns.
+	arrayFields schema.Fields
+	opts        *pgsql.Options
+	exec        *internal.Executor
+}
+
+func NewStore(table string, db *sql.DB, sc *schema.Schema, opts ...pgsql.Option) PostgresStorer {
+	o := pgsql.NewOptions(opts...)
+	s := &store{
+		table:   table,
+		dialect: goqu.Dialect("postgres"),
+		schema:  sc,
+		opts:    o,
+		exec:    internal.NewExecutor(table, db, o),
+	}
+	s.jsonFields, s.arrayFields = getColumnFields(sc.Fields, o)
+
+	return s
 }
 
-func NewStore(table string, db *sql.DB, sc *schema.Schema) PostgresStorer {
+// NewTenantStore returns a store running its statements on the database
+// returned by resolve for each request, e.g. a pgsql.TenantPools. Every
+// database is migrated on first use, so AutoMigrate is not needed.
+func NewTenantStore(table string, resolve pgsql.DBResolver, sc *schema.Schema, opts ...pgsql.Option) PostgresStorer {
+	o := pgsql.NewOptions(opts...)
 	s := &store{
-		table:      table,
-		db:         db,
-		dialect:    goqu.Dialect("postgres"),
-		schema:     sc,
-		jsonFields: getJsonFields(sc.Fields),
+		table:   table,
+		dialect: goqu.Dialect("postgres"),
+		schema:  sc,
+		opts:    o,
+		exec:    &internal.Executor{Table: table, Resolve: resolve, Options: o},
+	}
+	s.jsonFields, s.arrayFields = getColumnFields(sc.Fields, o)
+	s.exec.Migrate = func(ctx context.Context, db *sql.DB) error {
+		return s.migrate(ctx, db, s.schema)
 	}
 
 	return s
 }
 
+// getColumnFields returns the fields stored in JSONB columns and, with
+// native arrays, the ones stored in native array columns.
+func getColumnFields(fields schema.Fields, o *pgsql.Options) (jsonFields, arrayFields schema.Fields) {
+	jsonFields = getJsonFields(fields)
+	if !o.NativeArrays {
+		return jsonFields, nil
+	}
+
+	arrayFields = schema.Fields{}
+	for name, field := range jsonFields {
+		if nativeArray(field, o) {
+			arrayFields[name] = field
+			delete(jsonFields, name)
+		}
+	}
+	return jsonFields, arrayFields
+}
+
+// nativeArray tells if the top level field is stored in a native array
+// column.
+func nativeArray(field schema.Field, o *pgsql.Options) bool {
+	array, ok := field.Validator.(*schema.Array)
+	return ok && o.NativeArrays && internal.NativeArrayType(array, o) != ""
+}
+
 func getJsonFields(fields schema.Fields) schema.Fields {
 	jsonColumns := make(map[string]schema.Field, 0)
 	for name, field := range fields {
@@ -2,43 +2,45 @@ package pgsql
 
 import (
 	"context"
-	"log/slog"
 	"reflect"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/rs/rest-layer/resource"
-	"github.com/rs/rest-layer/rest"
 
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
 	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
-func (s store) Update(ctx context.Context, item *resource.Item, original *resource.Item) error {
+func (s store) Update(ctx context.Context, item *resource.Item, original *resource.Item) (err error) {
+	defer internal.Measure(s.opts, s.table, "Update", time.Now(), &err)
+
 	sqlStr, args, err := s.buildUpdateQuery(item, original)
 	if err != nil {
 		return err
 	}
 
-	slog.DebugContext(ctx, "pgsql.Update", "sql", sqlStr, "args", args)
-
-	affect, err := s.db.ExecContext(ctx, sqlStr, args...)
-	if err != nil {
-		return err
-	}
+	return s.exec.RunWrite(ctx, "Update", func(ctx context.Context, q internal.Querier) error {
+		affect, err := q.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
 
-	count, err := affect.RowsAffected()
-	if err != nil {
-		return err
-	}
+		count, err := affect.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	if count != 1 {
-		return rest.ErrPreconditionFailed
-	}
+		if count != 1 {
+			return internal.Conflict(ctx, q, s.table, "_etag", item.ID, internal.Live(s.opts)...)
+		}
 
-	return nil
+		return internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationUpdate, original, item)
+	})
 }
 
 func (s store) buildUpdateQuery(i *resource.Item, o *resource.Item) (string, []any, error) {
-	row := internal.CopyRow(i.Payload)
+	row := internal.CopyRow(internal.UTCPayload(s.opts, i.Payload))
 	delete(row, "id")
 
 	for k, v := range o.Payload {
@@ -53,9 +55,13 @@ func (s store) buildUpdateQuery(i *resource.Item, o *resource.Item) (string, []a
 	if err != nil {
 		return "", nil, err
 	}
+	toNativeArrays(s.arrayFields, row)
 
 	row["_etag"] = i.ETag
 	builder := s.dialect.Update(s.table).Where(goqu.L("_etag").Eq(o.ETag), goqu.L("id").Eq(i.ID)).Set(row)
+	if s.opts.SoftDelete {
+		builder = builder.Where(goqu.C(pgsql.DeletedColumn).IsNull())
+	}
 
 	sqlStr, args, err := builder.Prepared(true).ToSQL()
 	if err != nil {
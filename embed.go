@@ -0,0 +1,65 @@
+package pgsql
+
+import (
+	"context"
+
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/schema/query"
+)
+
+// EmbeddedReference tells where the items of a referenced resource are
+// stored, for them to be embedded by the stores referencing it.
+type EmbeddedReference struct {
+	// Path is the path of the referenced resource, as in schema.Reference.
+	Path string
+	// Table is the table of the referenced items, which must live in the
+	// same database as the referencing ones. Its layout may be any of the
+	// classic, jsonb and hybrid ones.
+	Table string
+	// IDType is the type of the id column of Table, e.g. INTEGER for
+	// SerialID, and TEXT when empty.
+	IDType string
+}
+
+// WithEmbeddedReferences makes the referenced resources of refs embeddable:
+// a Find with a context created by EmbedReferences resolves the references
+// projected with children in the same query, with LEFT JOIN LATERAL.
+func WithEmbeddedReferences(refs ...EmbeddedReference) Option {
+	return func(o *Options) {
+		if o.EmbeddedReferences == nil {
+			o.EmbeddedReferences = map[string]EmbeddedReference{}
+		}
+		for _, ref := range refs {
+			o.EmbeddedReferences[ref.Path] = ref
+		}
+	}
+}
+
+type embedKey struct{}
+
+// EmbedReferences returns a context making Find replace the values of the
+// reference fields projected with children by the referenced items, projected
+// on these children, or by nil when the item does not exist. Only the
+// references made embeddable by WithEmbeddedReferences are embedded, nested
+// references keep their values.
+//
+// rest-layer never creates such a context: its list endpoints keep
+// resolving references with one lookup per referenced resource. Embedding
+// is for custom handlers only, and these must not evaluate the projection
+// with query.Projection.Eval on the found items, which expects reference
+// values and not the items replacing them.
+func EmbedReferences(ctx context.Context) context.Context {
+	return context.WithValue(ctx, embedKey{}, true)
+}
+
+// EmbedsReferences tells if ctx was created by EmbedReferences.
+func EmbedsReferences(ctx context.Context) bool {
+	embed, _ := ctx.Value(embedKey{}).(bool)
+	return embed
+}
+
+// Embedder is implemented by stores created with WithEmbeddedReferences.
+type Embedder interface {
+	// FindEmbedded is Find embedding the referenced items.
+	FindEmbedded(ctx context.Context, q *query.Query) (*resource.ItemList, error)
+}
@@ -0,0 +1,33 @@
+package pgsql
+
+import (
+	"net/http"
+
+	"github.com/rs/rest-layer/rest"
+)
+
+// ETagIssue is the issue of the errors returned on etag mismatches, holding
+// the etag of the stored item.
+const ETagIssue = "_etag"
+
+// NewPreconditionFailed returns the error of an Update, Delete or Restore
+// whose etag does not match current, the etag of the stored item. It is
+// reported like rest.ErrPreconditionFailed, with current as ETagIssue.
+func NewPreconditionFailed(current string) *rest.Error {
+	return &rest.Error{
+		Code:    http.StatusPreconditionFailed,
+		Message: rest.ErrPreconditionFailed.Message,
+		Issues:  map[string][]any{ETagIssue: {current}},
+	}
+}
+
+// CurrentETag returns the etag of the stored item reported by err, when err
+// was returned on an etag mismatch.
+func CurrentETag(err error) (string, bool) {
+	e, ok := err.(*rest.Error)
+	if !ok || e.Code != http.StatusPreconditionFailed || len(e.Issues[ETagIssue]) == 0 {
+		return "", false
+	}
+	current, ok := e.Issues[ETagIssue][0].(string)
+	return current, ok
+}
@@ -0,0 +1,19 @@
+package pgsql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rs/rest-layer/rest"
+)
+
+func TestCurrentETag(t *testing.T) {
+	if current, ok := CurrentETag(NewPreconditionFailed("abc")); !ok || current != "abc" {
+		t.Errorf("CurrentETag = %q, %v, want abc, true", current, ok)
+	}
+	for _, err := range []error{rest.ErrPreconditionFailed, rest.ErrNotFound, errors.New("failed")} {
+		if _, ok := CurrentETag(err); ok {
+			t.Errorf("CurrentETag(%v) reported an etag", err)
+		}
+	}
+}
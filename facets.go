@@ -0,0 +1,24 @@
+package pgsql
+
+import (
+	"context"
+
+	"github.com/rs/rest-layer/schema/query"
+)
+
+// FacetValue is a distinct value of a facet field and the number of items
+// holding it.
+type FacetValue struct {
+	Value any   `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// Faceter is implemented by the stores counting facets.
+type Faceter interface {
+	// Facets returns, for each of fields, the distinct values held by the
+	// items matching the predicate of q with their number of items, the
+	// most frequent first. The values of array fields are their elements
+	// and the ones of dict fields their keys. Items without a value are not
+	// counted. fields must be filterable and not hidden.
+	Facets(ctx context.Context, q *query.Query, fields []string) (map[string][]FacetValue, error)
+}
@@ -0,0 +1,149 @@
+package pgsql
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// EarthRadius is the mean radius of the Earth, in meters, distances are
+// computed with.
+const EarthRadius = 6371008.8
+
+// The predicates below apply to the point fields, whose validator is a
+// PostgresTyper of type POINT, e.g. pgtypes.Point. They need no extension:
+// points are stored in native point columns, or computed from the lat/lng
+// objects of JSONB payloads, indexed with GiST by Migrate.
+
+// WithinBox matches the items whose point Field lies within the box of the
+// given bounds, in degrees. A box whose West is greater than its East crosses
+// the antimeridian.
+type WithinBox struct {
+	Field string
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// Match implements query.Expression.
+func (e WithinBox) Match(payload map[string]any) bool {
+	lat, lng, ok := pointField(payload, e.Field)
+	if !ok || lat < e.South || lat > e.North {
+		return false
+	}
+	if e.West <= e.East {
+		return lng >= e.West && lng <= e.East
+	}
+	return lng >= e.West || lng <= e.East
+}
+
+// Prepare implements query.Expression.
+func (e *WithinBox) Prepare(validator schema.Validator) error {
+	if err := pointValidator(e.Field, validator); err != nil {
+		return err
+	}
+	if !validLatitude(e.South) || !validLatitude(e.North) || e.South > e.North ||
+		!validLongitude(e.West) || !validLongitude(e.East) {
+		return fmt.Errorf("%s: invalid box", e.Field)
+	}
+	return nil
+}
+
+// String implements query.Expression.
+func (e WithinBox) String() string {
+	return fmt.Sprintf("%s: {$box: {south: %g, west: %g, north: %g, east: %g}}", e.Field, e.South, e.West, e.North, e.East)
+}
+
+// Near matches the items whose point Field lies within Radius meters of the
+// point at Lat and Lng, in degrees. Sorting on Field orders the items by
+// distance to that point, nearest first, and farthest first when reversed.
+type Near struct {
+	Field  string
+	Lat    float64
+	Lng    float64
+	Radius float64
+}
+
+// Match implements query.Expression.
+func (e Near) Match(payload map[string]any) bool {
+	lat, lng, ok := pointField(payload, e.Field)
+	return ok && Distance(e.Lat, e.Lng, lat, lng) <= e.Radius
+}
+
+// Prepare implements query.Expression.
+func (e *Near) Prepare(validator schema.Validator) error {
+	if err := pointValidator(e.Field, validator); err != nil {
+		return err
+	}
+	if !validLatitude(e.Lat) || !validLongitude(e.Lng) {
+		return fmt.Errorf("%s: invalid point", e.Field)
+	}
+	if e.Radius < 0 {
+		return fmt.Errorf("%s: negative radius", e.Field)
+	}
+	return nil
+}
+
+// String implements query.Expression.
+func (e Near) String() string {
+	return fmt.Sprintf("%s: {$near: {lat: %g, lng: %g, radius: %g}}", e.Field, e.Lat, e.Lng, e.Radius)
+}
+
+// Distance returns the great-circle distance, in meters, between two points
+// given in degrees.
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func pointValidator(field string, validator schema.Validator) error {
+	f := validator.GetField(field)
+	if f == nil {
+		return fmt.Errorf("%s: unknown query field", field)
+	}
+	if !f.Filterable {
+		return fmt.Errorf("%s: field is not filterable", field)
+	}
+	if t, ok := f.Validator.(PostgresTyper); !ok || t.PostgresType() != "POINT" {
+		return fmt.Errorf("%s: not a point", field)
+	}
+	return nil
+}
+
+// pointField returns the coordinates of the point at field in payload: a
+// value with Coordinates, e.g. a pgtypes.LatLng, or a lat/lng object.
+func pointField(payload map[string]any, field string) (lat, lng float64, ok bool) {
+	var value any = payload
+	for _, name := range strings.Split(field, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return 0, 0, false
+		}
+		value = m[name]
+	}
+
+	switch t := value.(type) {
+	case interface{ Coordinates() (float64, float64) }:
+		lat, lng = t.Coordinates()
+		return lat, lng, true
+	case map[string]any:
+		lat, ok1 := t["lat"].(float64)
+		lng, ok2 := t["lng"].(float64)
+		return lat, lng, ok1 && ok2
+	}
+	return 0, 0, false
+}
+
+func validLatitude(lat float64) bool {
+	return lat >= -90 && lat <= 90
+}
+
+func validLongitude(lng float64) bool {
+	return lng >= -180 && lng <= 180
+}
@@ -0,0 +1,75 @@
+package pgsql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+type point struct{ schema.String }
+
+func (point) PostgresType() string { return "POINT" }
+
+type latLng struct{ lat, lng float64 }
+
+func (p latLng) Coordinates() (float64, float64) { return p.lat, p.lng }
+
+func TestDistance(t *testing.T) {
+	// Paris to London
+	if d := Distance(48.8566, 2.3522, 51.5074, -0.1278); math.Abs(d-343_500) > 1_000 {
+		t.Errorf("Distance() = %f, want about 343.5 km", d)
+	}
+	// Antipodal points, where rounding takes the haversine above 1
+	if d := Distance(41.214, -47.598, -41.214, 132.402); math.IsNaN(d) || math.Abs(d-math.Pi*EarthRadius) > 1 {
+		t.Errorf("Distance() = %f, want half the circumference", d)
+	}
+}
+
+func TestGeoPredicates_Match(t *testing.T) {
+	payload := map[string]any{
+		"home":   map[string]any{"lat": 48.8566, "lng": 2.3522},
+		"office": latLng{51.5074, -0.1278},
+		"name":   "john",
+	}
+	tests := []struct {
+		name string
+		e    interface{ Match(map[string]any) bool }
+		want bool
+	}{
+		{"WithinBox", WithinBox{Field: "home", South: 48, West: 2, North: 49, East: 3}, true},
+		{"WithinBox: outside", WithinBox{Field: "home", South: 49, West: 2, North: 50, East: 3}, false},
+		{"WithinBox: antimeridian", WithinBox{Field: "office", South: 51, West: 170, North: 52, East: 0}, true},
+		{"WithinBox: not a point", WithinBox{Field: "name", South: -90, West: -180, North: 90, East: 180}, false},
+		{"Near", Near{Field: "office", Lat: 48.8566, Lng: 2.3522, Radius: 350_000}, true},
+		{"Near: too far", Near{Field: "office", Lat: 48.8566, Lng: 2.3522, Radius: 300_000}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.Match(payload); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoPredicates_Prepare(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"name": {Validator: &schema.String{}, Filterable: true},
+			"home": {Validator: point{}, Filterable: true},
+		},
+	}
+	if err := (&Near{Field: "home", Lat: 48.8, Lng: 2.3, Radius: 1000}).Prepare(sc); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := (&Near{Field: "name", Radius: 1000}).Prepare(sc); err == nil {
+		t.Error("Expected an error for a field not a point")
+	}
+	if err := (&Near{Field: "home", Lat: 91}).Prepare(sc); err == nil {
+		t.Error("Expected an error for an invalid latitude")
+	}
+	if err := (&WithinBox{Field: "home", South: 10, North: 0}).Prepare(sc); err == nil {
+		t.Error("Expected an error for an invalid box")
+	}
+}
@@ -0,0 +1,115 @@
+package pgsql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// History operations.
+const (
+	OperationInsert = "insert"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// HistorySuffix is appended to the store table name to name its history
+// table.
+const HistorySuffix = "_history"
+
+// WithHistory makes Insert, Update, Delete and Clear record every change in
+// the <table>_history table, in the same transaction as the change itself.
+func WithHistory() Option {
+	return func(o *Options) {
+		o.History = true
+	}
+}
+
+// ErrHistoryDisabled is returned by HistoryReader methods of stores created
+// without WithHistory.
+var ErrHistoryDisabled = errors.New("history is not enabled on this store")
+
+type actorKey struct{}
+
+// NewActorContext returns a context attributing the changes made with it to
+// actor in the history table.
+func NewActorContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by NewActorContext, if any.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Revision is a row of a history table. Old fields are empty for inserts and
+// New fields are empty for deletes.
+type Revision struct {
+	Revision   int64
+	ItemID     string
+	Operation  string
+	Actor      string
+	ChangedAt  time.Time
+	OldETag    string
+	NewETag    string
+	OldUpdated time.Time
+	NewUpdated time.Time
+	OldPayload map[string]any
+	NewPayload map[string]any
+}
+
+// HistoryReader is implemented by stores created with WithHistory.
+type HistoryReader interface {
+	// Revisions returns the revisions of an item, oldest first.
+	Revisions(ctx context.Context, id any) ([]*Revision, error)
+}
+
+// FieldChange is a difference between two payloads. Path is the dotted path
+// of the field; Old or New is nil when the field was added or removed.
+type FieldChange struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// Diff returns the field level changes between the item state after from and
+// after to, sorted by path. Nested objects are compared field by field.
+func Diff(from, to *Revision) []FieldChange {
+	var changes []FieldChange
+	diffPayloads("", from.NewPayload, to.NewPayload, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffPayloads(prefix string, from, to map[string]any, changes *[]FieldChange) {
+	for key, oldValue := range from {
+		path := prefix + key
+		newValue, ok := to[key]
+		if !ok {
+			*changes = append(*changes, FieldChange{Path: path, Old: oldValue})
+			continue
+		}
+
+		oldMap, oldIsMap := oldValue.(map[string]any)
+		newMap, newIsMap := newValue.(map[string]any)
+		if oldIsMap && newIsMap {
+			diffPayloads(path+".", oldMap, newMap, changes)
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue, newValue) {
+			*changes = append(*changes, FieldChange{Path: path, Old: oldValue, New: newValue})
+		}
+	}
+
+	for key, newValue := range to {
+		if _, ok := from[key]; !ok {
+			*changes = append(*changes, FieldChange{Path: prefix + key, New: newValue})
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package pgsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := &Revision{NewPayload: map[string]any{
+		"name":    "John",
+		"age":     30.0,
+		"removed": true,
+		"address": map[string]any{
+			"city":  "Sofia",
+			"state": "SF",
+		},
+		"tags": []any{"a", "b"},
+	}}
+	to := &Revision{NewPayload: map[string]any{
+		"name":  "John",
+		"age":   31.0,
+		"added": "yes",
+		"address": map[string]any{
+			"city":  "Plovdiv",
+			"state": "SF",
+		},
+		"tags": []any{"a"},
+	}}
+
+	want := []FieldChange{
+		{Path: "added", New: "yes"},
+		{Path: "address.city", Old: "Sofia", New: "Plovdiv"},
+		{Path: "age", Old: 30.0, New: 31.0},
+		{Path: "removed", Old: true},
+		{Path: "tags", Old: []any{"a", "b"}, New: []any{"a"}},
+	}
+	if got := Diff(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %#v, want %#v", got, want)
+	}
+
+	if got := Diff(to, to); len(got) != 0 {
+		t.Errorf("Diff() of identical revisions = %#v, want none", got)
+	}
+}
@@ -0,0 +1,44 @@
+package pgsql
+
+import (
+	"context"
+	"time"
+)
+
+// QueryEvent describes a statement run by a store.
+type QueryEvent struct {
+	// Operation is the store operation running the statement, e.g. "Find",
+	// "Insert" or "Migrate".
+	Operation string
+	// Table is the table of the store.
+	Table string
+	SQL   string
+	Args  []any
+	// Duration is the time the statement took, set after it ran. For
+	// queries, reading the rows is not included.
+	Duration time.Duration
+	// RowsAffected is the number of rows changed by the statement, set after
+	// it ran, or -1 for queries returning rows.
+	RowsAffected int64
+	// Err is the error of the statement, set after it ran.
+	Err error
+}
+
+// QueryHook is notified of every statement run by a store, e.g. to open
+// tracing spans, log or assert them in tests.
+type QueryHook interface {
+	// BeforeQuery is called before the statement runs. The statement runs
+	// with the returned context, which is then passed to AfterQuery.
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	// AfterQuery is called once the statement ran, with the same event.
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// WithQueryHook makes the store notify hook of every statement it runs. The
+// hooks are called in the order they were added before the statement, and
+// in the reverse order after it.
+func WithQueryHook(hook QueryHook) Option {
+	return func(o *Options) {
+		o.QueryHooks = append(o.QueryHooks, hook)
+	}
+}
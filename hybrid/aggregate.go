@@ -0,0 +1,60 @@
+package hybrid
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Aggregate(ctx context.Context, q *query.Query, spec pgsql.AggregateSpec) ([]pgsql.AggregateRow, error) {
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	builder := s.dialect.From(source)
+	if err := s.buildWheres(q, builder); err != nil {
+		return nil, errors.Wrapf(err, "predicate: %v", q.Predicate)
+	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
+	err = internal.PrepareAggregate(builder, s.schema, q, spec, s.typedValue)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []pgsql.AggregateRow
+	err = s.exec.Run(ctx, "Aggregate", func(ctx context.Context, querier internal.Querier) error {
+		result, err := querier.QueryContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+
+		rows, err = internal.ScanAggregate(result, s.schema, spec)
+		return err
+	})
+	return rows, err
+}
+
+// typedValue returns the expression of field cast to the type of its values.
+func (s store) typedValue(field string) exp.Expression {
+	return goqu.L("?", s.typedField("", s.schema, field))
+}
+
+// jsonValue returns the JSONB value of field.
+func (s store) jsonValue(field string) exp.Expression {
+	return s.field("", field, true)
+}
@@ -0,0 +1,76 @@
+package hybrid
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Clear(ctx context.Context, q *query.Query) (count int, err error) {
+	defer internal.Measure(s.opts, s.table, "Clear", time.Now(), &err)
+
+	ctx = internal.WithQuery(ctx, q)
+	expressions, err := s.predicteToExpressions("", s.schema, q.Predicate)
+	if err != nil {
+		return 0, err
+	}
+
+	var removal exp.AppendableExpression
+	if s.opts.SoftDelete {
+		expressions = append(expressions, goqu.C(pgsql.DeletedColumn).IsNull())
+		removal = s.dialect.Update(s.table).Set(internal.SoftDeleteRecord()).Where(expressions...).Returning(goqu.Star())
+	} else {
+		removal = s.dialect.Delete(s.table).Where(expressions...).Returning(goqu.Star())
+	}
+
+	sqlStr, args, err := internal.PrepareClear(ctx, s.table, s.opts, removal, "etag", "updated", s.removedPayload())
+	if err != nil {
+		return
+	}
+
+	err = s.exec.RunWrite(ctx, "Clear", func(ctx context.Context, q internal.Querier) error {
+		changes, err := internal.ExecClear(ctx, q, s.table, sqlStr, args)
+		if err != nil {
+			return err
+		}
+		count = len(changes)
+
+		if err := internal.WriteEvents(ctx, q, s.opts, changes...); err != nil {
+			return err
+		}
+		return internal.Publish(ctx, q, s.opts, changes...)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// removedPayload returns the full payload of the rows removed by a Clear,
+// with their id and promoted columns.
+func (s store) removedPayload() exp.Expression {
+	names := make([]string, 0, len(s.columns))
+	for name := range s.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	placeholders := []string{"'id', ?"}
+	args := []any{goqu.I("removed.id")}
+	for _, name := range names {
+		placeholders = append(placeholders, "?::text, ?")
+		args = append(args, name, goqu.I("removed."+name))
+	}
+
+	return goqu.L("? || jsonb_strip_nulls(jsonb_build_object("+strings.Join(placeholders, ", ")+"))",
+		append([]any{goqu.I("removed.payload")}, args...)...)
+}
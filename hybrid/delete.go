@@ -0,0 +1,51 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func prepareDelete(item *resource.Item) exp.Expression {
+	return goqu.And(goqu.C("id").Eq(item.ID), goqu.C("etag").Eq(item.ETag))
+}
+
+func (s store) Delete(ctx context.Context, item *resource.Item) (err error) {
+	defer internal.Measure(s.opts, s.table, "Delete", time.Now(), &err)
+
+	var sqlStr string
+	var args []any
+	if s.opts.SoftDelete {
+		sqlStr, args, err = s.dialect.Update(s.table).Set(internal.SoftDeleteRecord()).
+			Where(prepareDelete(item), goqu.C(pgsql.DeletedColumn).IsNull()).Prepared(true).ToSQL()
+	} else {
+		sqlStr, args, err = s.dialect.Delete(s.table).Where(prepareDelete(item)).Prepared(true).ToSQL()
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.exec.RunWrite(ctx, "Delete", func(ctx context.Context, q internal.Querier) error {
+		affect, err := q.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := affect.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return internal.Conflict(ctx, q, s.table, "etag", item.ID, internal.Live(s.opts)...)
+		}
+
+		return internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationDelete, item, nil)
+	})
+}
@@ -0,0 +1,506 @@
+package hybrid
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Find(ctx context.Context, q *query.Query) (list *resource.ItemList, err error) {
+	defer internal.Measure(s.opts, s.table, "Find", time.Now(), &err)
+
+	limit := 10
+	if q.Window != nil {
+		limit = q.Window.Limit
+	}
+
+	result := &resource.ItemList{
+		Total: -1,
+		Limit: limit,
+		Items: []*resource.Item{},
+	}
+
+	err = s.reduce(ctx, q, func(item *resource.Item) error {
+		result.Items = append(result.Items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s store) FindEmbedded(ctx context.Context, q *query.Query) (*resource.ItemList, error) {
+	return s.Find(pgsql.EmbedReferences(ctx), q)
+}
+
+func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *resource.Item) error) (err error) {
+	defer internal.Measure(s.opts, s.table, "Reduce", time.Now(), &err)
+
+	return s.reduce(ctx, q, reducer)
+}
+
+// reduce is Reduce, without recording the operation, for Find.
+func (s store) reduce(ctx context.Context, q *query.Query, reducer func(item *resource.Item) error) error {
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
+	if err != nil {
+		return err
+	}
+	builder := s.dialect.From(source).Select(goqu.Star())
+	if pgsql.EmbedsReferences(ctx) {
+		// Items are not projected, so neither are the names of their fields
+		pj := make(query.Projection, len(q.Projection))
+		for i, pf := range q.Projection {
+			pj[i] = pf
+			pj[i].Alias = ""
+		}
+		internal.Embed(builder, s.table, s.schema, pj, s.opts.EmbeddedReferences, func(name string) exp.Expression {
+			if _, promoted := s.columns[name]; promoted {
+				return goqu.I(s.table + "." + name)
+			}
+			return goqu.L("?->>?", goqu.I(s.table+".payload"), name)
+		})
+	}
+	if err := s.buildWheres(q, builder); err != nil {
+		return errors.Wrapf(err, "predicate: %v", q.Predicate)
+	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
+	if err := s.buildSorts(q, builder); err != nil {
+		return err
+	}
+	buildPagination(q, builder)
+
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "predicate: %v", q.Predicate)
+	}
+
+	return s.exec.Run(ctx, "Find", func(ctx context.Context, querier internal.Querier) error {
+		rows, err := querier.QueryContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		if err := s.scanItems(rows, reducer); err != nil {
+			return err
+		}
+		return rows.Err()
+	})
+}
+
+// scanItems maps every row to a resource.Item, merging the promoted columns
+// back into the payload, and hands it to fn.
+func (s store) scanItems(rows *sql.Rows, fn func(item *resource.Item) error) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		rowVals := make([]any, len(cols))
+		rowValPtrs := make([]any, len(cols))
+
+		for i := range cols {
+			rowValPtrs[i] = &rowVals[i]
+		}
+
+		err := rows.Scan(rowValPtrs...)
+		if err != nil {
+			return err
+		}
+
+		var ID any
+		var etag string
+		var updated time.Time
+		payload := make(map[string]any)
+		columns := make(map[string]any)
+		embedded := make(map[string]any)
+
+		for i, v := range rowVals {
+			b, ok := v.([]byte)
+			if ok && !internal.Binary(s.columns[cols[i]]) {
+				v = string(b)
+			}
+
+			switch cols[i] {
+			case "id":
+				if ID, err = s.ids().ParseID(v); err != nil {
+					return err
+				}
+			case "etag":
+				if v != nil {
+					etag = v.(string)
+				}
+			case "updated":
+				if v != nil {
+					updated = internal.UTC(s.opts, v).(time.Time)
+				}
+			case "payload":
+				if v != nil {
+					if err := json.Unmarshal([]byte(v.(string)), &payload); err != nil {
+						return err
+					}
+				}
+			default:
+				if name, ok := strings.CutPrefix(cols[i], internal.EmbeddedPrefix); ok {
+					embedded[name] = v
+					continue
+				}
+				field, promoted := s.columns[cols[i]]
+				if !promoted || v == nil {
+					continue
+				}
+				if isJSONField(field) {
+					var value any
+					if err := json.Unmarshal([]byte(v.(string)), &value); err != nil {
+						return err
+					}
+					v = value
+				}
+				columns[cols[i]] = internal.UTC(s.opts, v)
+			}
+		}
+
+		for name, value := range columns {
+			payload[name] = value
+		}
+		payload["id"] = ID
+		if err := internal.MergeEmbedded(payload, embedded); err != nil {
+			return err
+		}
+
+		item := &resource.Item{
+			ID:      ID,
+			ETag:    etag,
+			Updated: updated,
+			Payload: payload,
+		}
+		internal.FixSchemaTypes(s.schema, item.Payload)
+
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s store) Count(ctx context.Context, q *query.Query) (total int, err error) {
+	defer internal.Measure(s.opts, s.table, "Count", time.Now(), &err)
+
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
+	if err != nil {
+		return 0, err
+	}
+	builder := s.dialect.From(source).Select(goqu.COUNT(goqu.Star()))
+	if err := s.buildWheres(q, builder); err != nil {
+		return 0, err
+	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
+
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = s.exec.Run(ctx, "Count", func(ctx context.Context, querier internal.Querier) error {
+		return querier.QueryRowContext(ctx, sqlStr, args...).Scan(&count)
+	})
+
+	return count, err
+}
+
+func buildPagination(q *query.Query, builder *goqu.SelectDataset) {
+	limit := 20
+	offset := 0
+
+	window := q.Window
+	if window != nil {
+		limit = window.Limit
+		offset = window.Offset
+	}
+	*builder = *builder.Limit(uint(limit))
+	*builder = *builder.Offset(uint(offset))
+}
+
+// buildSorts orders builder by the sort of q.
+func (s store) buildSorts(q *query.Query, builder *goqu.SelectDataset) error {
+	for _, field := range q.Sort {
+		if search, ok := internal.SearchValidator(s.schema, field.Name); ok {
+			if rank, ok := internal.SearchRank(search, field.Name, q.Predicate); ok {
+				if field.Reversed {
+					*builder = *builder.OrderAppend(rank.Desc())
+				} else {
+					*builder = *builder.OrderAppend(rank.Asc())
+				}
+			}
+			continue
+		}
+		if internal.PointField(s.schema, field.Name) {
+			distance, err := internal.GeoDistance(field.Name, q.Predicate, s.pointField("", field.Name))
+			if err != nil {
+				return err
+			}
+			if field.Reversed {
+				*builder = *builder.OrderAppend(distance.Desc())
+			} else {
+				*builder = *builder.OrderAppend(distance.Asc())
+			}
+			continue
+		}
+
+		expr := s.typedField("", s.schema, field.Name)
+		if field.Reversed {
+			*builder = *builder.OrderAppend(expr.Desc())
+		} else {
+			*builder = *builder.OrderAppend(expr.Asc())
+		}
+	}
+	return nil
+}
+
+func (s store) buildWheres(q *query.Query, builder *goqu.SelectDataset) error {
+	expressions, err := s.predicteToExpressions("", s.schema, q.Predicate)
+	if err != nil {
+		return err
+	}
+	*builder = *builder.Where(expressions...)
+	return nil
+}
+
+// predicteToExpressions translates q. Within an $elemMatch, parent is the
+// alias of the array element and sc the schema of the element.
+func (s store) predicteToExpressions(parent string, sc *schema.Schema, q query.Predicate) (expressions []exp.Expression, err error) {
+	for _, e := range q {
+		if expr, ok := internal.GeoPredicate(e, func(field string) exp.Expression {
+			return s.pointField(parent, field)
+		}); ok {
+			expressions = append(expressions, expr)
+			continue
+		}
+
+		expr, ok, err := internal.ArrayPredicate(sc, e, func(field string) exp.Expression {
+			return s.field(parent, field, true)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			expressions = append(expressions, expr)
+			continue
+		}
+
+		switch t := e.(type) {
+		case *query.And:
+			var exprs []exp.Expression
+			for _, subExp := range *t {
+				var sube []exp.Expression
+				sube, err = s.predicteToExpressions(parent, sc, query.Predicate{subExp})
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, sube...)
+			}
+			expressions = append(expressions, goqu.And(exprs...))
+
+		case *query.Or:
+			var exprs []exp.Expression
+			for _, subExp := range *t {
+				var sube []exp.Expression
+				sube, err = s.predicteToExpressions(parent, sc, query.Predicate{subExp})
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, sube...)
+			}
+			expressions = append(expressions, goqu.Or(exprs...))
+
+		case *query.In:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).In(t.Values))
+
+		case *query.NotIn:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).NotIn(t.Values))
+
+		case *query.Equal:
+			if search, ok := internal.SearchValidator(sc, t.Field); ok && parent == "" {
+				expressions = append(expressions, internal.SearchMatch(search, t.Value))
+				continue
+			}
+
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).Eq(t.Value))
+
+		case *query.NotEqual:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).Neq(t.Value))
+
+		case *query.GreaterThan:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).Gt(t.Value))
+
+		case *query.GreaterOrEqual:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).Gte(t.Value))
+
+		case *query.LowerThan:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).Lt(t.Value))
+
+		case *query.LowerOrEqual:
+			expressions = append(expressions, s.typedField(parent, sc, t.Field).Lte(t.Value))
+
+		case *query.Regex:
+			pattern, caseInsensitive, err := internal.TranslateRegex(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			field := s.field(parent, t.Field, false)
+			switch {
+			case t.Negated && caseInsensitive:
+				expressions = append(expressions, field.RegexpNotILike(pattern))
+			case t.Negated:
+				expressions = append(expressions, field.RegexpNotLike(pattern))
+			case caseInsensitive:
+				expressions = append(expressions, field.RegexpILike(pattern))
+			default:
+				expressions = append(expressions, field.RegexpLike(pattern))
+			}
+
+		case *query.Exist:
+			expressions = append(expressions, s.field(parent, t.Field, false).IsNotNull())
+
+		case *query.NotExist:
+			expressions = append(expressions, s.field(parent, t.Field, false).IsNull())
+
+		case *query.ElemMatch:
+			elemSchema, err := elementSchema(sc, t.Field)
+			if err != nil {
+				return nil, err
+			}
+			alias := elementAlias(parent, t.Field)
+			exprs := make([]exp.Expression, 0, len(t.Exps))
+			for _, p := range t.Exps {
+				var sube []exp.Expression
+				sube, err = s.predicteToExpressions(alias, elemSchema, query.Predicate{p})
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, goqu.And(sube...))
+			}
+			elements := goqu.Dialect("postgres").
+				From(goqu.L("jsonb_array_elements(?) AS ?(?)", s.field(parent, t.Field, true), goqu.I(alias), goqu.I(alias))).
+				Select(goqu.L("1")).
+				Where(exprs...)
+			expressions = append(expressions, goqu.L("EXISTS ?", elements))
+
+		default:
+			return nil, fmt.Errorf("unsupported predicate %T", t)
+		}
+	}
+	return
+}
+
+// field returns the expression of field, routed to its column: the id or a
+// promoted column, a path in a promoted JSONB column or a path in the
+// payload. Within an $elemMatch, parent is the alias of the array element.
+func (s store) field(parent, field string, asJSOBN bool) exp.LiteralExpression {
+	if parent != "" {
+		return jsonPath(parent, strings.Split(field, "."), asJSOBN)
+	}
+
+	column, _, _ := strings.Cut(field, ".")
+	if _, promoted := s.columns[column]; !promoted && column != "id" {
+		return jsonPath("payload", strings.Split(field, "."), asJSOBN)
+	}
+	if column == field {
+		return goqu.L("?", goqu.C(column))
+	}
+	return jsonPath(column, strings.Split(field, ".")[1:], asJSOBN)
+}
+
+// pointField returns the point expression of field: a promoted point column,
+// or the point of a lat/lng object in a promoted JSONB column or in the
+// payload. Within an $elemMatch, parent is the alias of the array element.
+func (s store) pointField(parent, field string) exp.Expression {
+	if parent != "" {
+		return goqu.L(internal.JSONPoint(parent, field))
+	}
+	return goqu.L(s.pointText(field))
+}
+
+// jsonPath returns the expression of path in the JSONB column, as JSONB or
+// as text.
+func jsonPath(column string, path []string, asJSOBN bool) exp.LiteralExpression {
+	literalText := "?"
+	exprs := []any{goqu.C(column)}
+	for i, str := range path {
+		if i == len(path)-1 && !asJSOBN {
+			literalText += "->>?"
+		} else {
+			literalText += "->?"
+		}
+		exprs = append(exprs, goqu.V(str))
+	}
+
+	return goqu.L(literalText, exprs...)
+}
+
+// fieldExpression is the comparable expression of a field.
+type fieldExpression interface {
+	exp.Comparable
+	exp.Inable
+	exp.Orderable
+}
+
+// typedField returns the expression of field, cast to the type of its schema
+// field when it is read as text from a JSONB column.
+func (s store) typedField(parent string, sc *schema.Schema, field string) fieldExpression {
+	expr := s.field(parent, field, false)
+	if _, promoted := s.columns[field]; (promoted || field == "id") && parent == "" {
+		return expr
+	}
+	if pgType := internal.CastType(sc, field, s.opts); pgType != "" {
+		return goqu.Cast(expr, pgType)
+	}
+	return expr
+}
+
+// elementSchema returns the schema of the objects of the array field.
+func elementSchema(s *schema.Schema, field string) (*schema.Schema, error) {
+	if s != nil {
+		if f := s.GetField(field); f != nil {
+			if array, ok := f.Validator.(*schema.Array); ok {
+				if object, ok := array.Values.Validator.(*schema.Object); ok {
+					return object.Schema, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("$elemMatch on %s: not an array of objects", field)
+}
+
+// elementAlias returns the alias of the elements of the array field.
+func elementAlias(parent, field string) string {
+	alias := strings.ReplaceAll(field, ".", "_")
+	if parent != "" {
+		alias = parent + "_" + alias
+	}
+	return alias
+}
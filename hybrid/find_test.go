@@ -0,0 +1,118 @@
+package hybrid
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/rs/rest-layer/schema/query"
+)
+
+func TestStore_buildWheres(t *testing.T) {
+	tests := []struct {
+		name       string
+		predicate  query.Predicate
+		wantSQL    string
+		wantArgs   []any
+		wantErrStr string
+	}{
+		{
+			name:      "query.Equal: id",
+			predicate: query.Predicate{&query.Equal{Field: "id", Value: "1"}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("id" = $1)`,
+			wantArgs:  []any{"1"},
+		},
+		{
+			name:      "query.Equal: column",
+			predicate: query.Predicate{&query.Equal{Field: "owner", Value: "alice"}},
+			wantSQL:   `SELECT * FROM "table" WHERE ("owner" = $1)`,
+			wantArgs:  []any{"alice"},
+		},
+		{
+			name:      "query.Equal: path in a column",
+			predicate: query.Predicate{&query.Equal{Field: "address.zip", Value: 1000}},
+			wantSQL:   `SELECT * FROM "table" WHERE (CAST("address"->>$1 AS INTEGER) = $2)`,
+			wantArgs:  []any{"zip", int64(1000)},
+		},
+		{
+			name:      "query.GreaterThan: payload, typed",
+			predicate: query.Predicate{&query.GreaterThan{Field: "age", Value: 30}},
+			wantSQL:   `SELECT * FROM "table" WHERE (CAST("payload"->>$1 AS INTEGER) > $2)`,
+			wantArgs:  []any{"age", int64(30)},
+		},
+		{
+			name:      "query.Equal: payload array",
+			predicate: query.Predicate{&query.Equal{Field: "tags", Value: "red"}},
+			wantSQL:   `SELECT * FROM "table" WHERE "payload"->$1 @> $2::jsonb`,
+			wantArgs:  []any{"tags", `["red"]`},
+		},
+		{
+			name: "query.Or",
+			predicate: query.Predicate{&query.Or{
+				&query.Equal{Field: "owner", Value: "alice"},
+				&query.Regex{Field: "name", Value: regexp.MustCompile("(?i)^jo")},
+			}},
+			wantSQL:  `SELECT * FROM "table" WHERE (("owner" = $1) OR ("payload"->>$2 ~* $3))`,
+			wantArgs: []any{"alice", "name", "^jo"},
+		},
+		{
+			name:       "query.ElemMatch: not an array of objects",
+			predicate:  query.Predicate{&query.ElemMatch{Field: "tags"}},
+			wantErrStr: "$elemMatch on tags: not an array of objects",
+		},
+	}
+
+	s := newTestStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := s.dialect.From("table")
+			err := s.buildWheres(&query.Query{Predicate: tt.predicate}, builder)
+			if tt.wantErrStr != "" {
+				if err == nil || err.Error() != tt.wantErrStr {
+					t.Fatalf("Error = %v, want %s", err, tt.wantErrStr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			sql, args, err := builder.Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			for i, arg := range args {
+				if v, ok := arg.(interface{ Value() (driver.Value, error) }); ok {
+					args[i], _ = v.Value()
+				}
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestStore_buildSorts(t *testing.T) {
+	s := newTestStore()
+	builder := s.dialect.From("table")
+	if err := s.buildSorts(&query.Query{Sort: query.Sort{{Name: "owner", Reversed: true}, {Name: "age"}}}, builder); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sql, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantSQL := `SELECT * FROM "table" ORDER BY "owner" DESC, CAST("payload"->>$1 AS INTEGER) ASC`
+	if sql != wantSQL {
+		t.Errorf("SQL = %s, want %s", sql, wantSQL)
+	}
+	if wantArgs := []any{"age"}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+}
@@ -0,0 +1,82 @@
+package hybrid
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Revisions(ctx context.Context, id any) (revisions []*pgsql.Revision, err error) {
+	if !s.opts.History {
+		return nil, pgsql.ErrHistoryDisabled
+	}
+
+	err = s.exec.Run(ctx, "Revisions", func(ctx context.Context, q internal.Querier) (err error) {
+		revisions, err = internal.ListRevisions(ctx, q, s.table, id)
+		return err
+	})
+	return revisions, err
+}
+
+func (s store) FindAsOf(ctx context.Context, q *query.Query, at time.Time) (*resource.ItemList, error) {
+	return s.Find(pgsql.NewAsOfContext(ctx, at), q)
+}
+
+func (s store) CountAsOf(ctx context.Context, q *query.Query, at time.Time) (int, error) {
+	return s.Count(pgsql.NewAsOfContext(ctx, at), q)
+}
+
+// source returns the relation reads select from: the table itself, or its
+// state rebuilt from the history table when ctx carries an as-of time. The
+// promoted columns are read back from the revision payloads.
+func (s store) source(ctx context.Context) (any, error) {
+	at, ok := pgsql.AsOfFromContext(ctx)
+	if !ok {
+		return goqu.T(s.table), nil
+	}
+	if !s.opts.History {
+		return nil, pgsql.ErrHistoryDisabled
+	}
+
+	names := make([]string, 0, len(s.columns))
+	for name := range s.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	payload := "? - 'id'"
+	columns := []any{
+		goqu.C("item_id").As("id"),
+		goqu.C("new_etag").As("etag"),
+		goqu.C("new_updated").As("updated"),
+		nil,
+	}
+	args := []any{goqu.C("new_payload")}
+	for _, name := range names {
+		payload += " - ?::text"
+		args = append(args, name)
+
+		field := s.columns[name]
+		pgType, err := internal.ColumnType(&field, s.opts)
+		if err != nil {
+			return nil, err
+		}
+		pgType = strings.TrimSuffix(pgType, " NOT NULL")
+		if isJSONField(field) {
+			columns = append(columns, goqu.L("?->?", goqu.C("new_payload"), name).As(name))
+		} else {
+			columns = append(columns, goqu.Cast(goqu.L("?->>?", goqu.C("new_payload"), name), pgType).As(name))
+		}
+	}
+	columns[3] = goqu.L(payload, args...).As("payload")
+
+	return internal.AsOf(s.table, at, columns...).As(s.table), nil
+}
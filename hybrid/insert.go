@@ -0,0 +1,90 @@
+package hybrid
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Insert(ctx context.Context, items []*resource.Item) (err error) {
+	defer internal.Measure(s.opts, s.table, "Insert", time.Now(), &err)
+
+	return s.exec.RunWrite(ctx, "Insert", func(ctx context.Context, q internal.Querier) error {
+		for _, item := range items {
+			if err := s.insertOne(ctx, q, item); err != nil {
+				return err
+			}
+			if err := internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationInsert, nil, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s store) prepareInsertQuery(item *resource.Item) (string, []any, error) {
+	columns, rest := s.splitPayload(internal.UTCPayload(s.opts, item.Payload))
+
+	payload, err := encodePayload(rest)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tableRow := goqu.Record{
+		"etag":    item.ETag,
+		"id":      item.ID,
+		"updated": item.Updated,
+		"payload": payload,
+	}
+	for name, value := range columns {
+		if tableRow[name], err = s.columnValue(name, value); err != nil {
+			return "", nil, err
+		}
+	}
+
+	builder := s.dialect.Insert(s.table)
+	if s.ids().Generated() {
+		delete(tableRow, "id")
+		builder = builder.Returning(goqu.C("id"))
+	}
+	return builder.Prepared(true).Rows(tableRow).ToSQL()
+}
+
+func (s store) insertOne(ctx context.Context, q internal.Querier, item *resource.Item) error {
+	sqlStr, args, err := s.prepareInsertQuery(item)
+	if err != nil {
+		return err
+	}
+
+	result := q.QueryRowContext(ctx, sqlStr, args...)
+	if result.Err() != nil {
+		return result.Err()
+	}
+
+	if ids := s.ids(); ids.Generated() {
+		var value any
+		if err := result.Scan(&value); err != nil {
+			return err
+		}
+		id, err := ids.ParseID(value)
+		if err != nil {
+			return err
+		}
+		item.Payload["id"] = id
+		item.ID = id
+	} else {
+		// Consume the result, in order to rows to be closed
+		result.Scan()
+	}
+
+	return nil
+}
+
+func (s store) ids() pgsql.IDStrategy {
+	return internal.IDs(s.schema, s.opts)
+}
@@ -0,0 +1,124 @@
+package hybrid
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/rotisserie/eris"
+	"github.com/rs/rest-layer/schema"
+
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Migrate(ctx context.Context, sc *schema.Schema) (err error) {
+	db, err := s.exec.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.migrate(ctx, db, sc)
+}
+
+func (s store) migrate(ctx context.Context, db *sql.DB, sc *schema.Schema) (err error) {
+	q := s.exec.Trace(db, "Migrate")
+	queries, err := s.buildMigrateQueries(sc)
+	if err != nil {
+		return err
+	}
+
+	rlsQueries, err := internal.RowSecurityQueries(s.table, s.opts.RowSecurity)
+	if err != nil {
+		return err
+	}
+	queries = append(queries, internal.SoftDeleteQueries(s.table, s.opts)...)
+	queries = append(queries, internal.HistoryQueries(s.table, s.opts)...)
+	queries = append(queries, internal.SearchQueries(s.table, sc, s.searchText)...)
+	queries = append(queries, internal.PointIndexQueries(s.table, sc, s.pointText)...)
+	queries = append(queries, internal.NotifyTriggerQueries(s.table, "etag", s.opts)...)
+	queries = append(queries, internal.OutboxQueries(s.opts)...)
+	queries = append(queries, rlsQueries...)
+	for _, query := range queries {
+		if _, err = q.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildMigrateQueries returns the statements creating the table, and adding
+// the promoted columns missing from an existing table with their index, and
+// converting its time columns with time zones. Values already stored in the
+// payload are not moved to added columns.
+func (s store) buildMigrateQueries(sc *schema.Schema) ([]string, error) {
+	table := pq.QuoteIdentifier(s.table)
+
+	fieldStrings := []string{"id " + s.ids().ColumnType()}
+	fieldStrings = append(fieldStrings, "etag VARCHAR(32)", "updated "+internal.TimeType(s.opts), "payload JSONB")
+
+	names := make([]string, 0, len(s.columns))
+	for name := range s.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var alters []string
+	timeColumns := []string{"updated"}
+	for _, name := range names {
+		field := s.columns[name]
+		if _, ok := field.Validator.(*schema.Time); ok {
+			timeColumns = append(timeColumns, name)
+		}
+		pgType, err := internal.ColumnType(&field, s.opts)
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to convert field %s to pg type", name)
+		}
+		column := pq.QuoteIdentifier(name)
+		fieldStrings = append(fieldStrings, column+" "+pgType)
+
+		// Existing rows have no value for added columns
+		pgType = strings.TrimSuffix(pgType, " NOT NULL")
+		alters = append(alters, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, pgType))
+		// Points have no B-tree index, their GiST one is created with the
+		// payload ones
+		if pgType != internal.PointType {
+			alters = append(alters, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", pq.QuoteIdentifier(s.table+"_"+name+"_idx"), table, column))
+		}
+	}
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s,PRIMARY KEY(id))", table, strings.Join(fieldStrings, ","))
+	alters = append(alters, internal.TimeZoneQueries(s.table, s.opts, timeColumns...)...)
+	queries := append(internal.ExtensionQueries(sc), create)
+	return append(queries, alters...), nil
+}
+
+// pointText returns the expression of the point at path: a promoted point
+// column, or the point of a lat/lng object in a promoted JSONB column or in
+// the payload.
+func (s store) pointText(path string) string {
+	column, nested, ok := strings.Cut(path, ".")
+	if _, promoted := s.columns[column]; !promoted {
+		return internal.JSONPoint("payload", path)
+	}
+	if !ok {
+		return pq.QuoteIdentifier(column)
+	}
+	return internal.JSONPoint(column, nested)
+}
+
+// searchText returns the expression of the text at path: a promoted column,
+// a path in a promoted JSONB column or a path in the payload.
+func (s store) searchText(path string) string {
+	column, nested, ok := strings.Cut(path, ".")
+	if _, promoted := s.columns[column]; !promoted {
+		return internal.JSONText("payload", path)
+	}
+	if !ok {
+		return pq.QuoteIdentifier(column)
+	}
+	return internal.JSONText(column, nested)
+}
@@ -0,0 +1,59 @@
+package hybrid
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/pgtypes"
+)
+
+func TestStore_buildMigrateQueries(t *testing.T) {
+	queries, err := newTestStore().buildMigrateQueries(testSchema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedQueries := []string{
+		`CREATE TABLE IF NOT EXISTS "table" (id VARCHAR(24),etag VARCHAR(32),updated TIMESTAMP,payload JSONB,"address" JSONB,"owner" VARCHAR(24) NOT NULL,PRIMARY KEY(id))`,
+		`ALTER TABLE "table" ADD COLUMN IF NOT EXISTS "address" JSONB`,
+		`CREATE INDEX IF NOT EXISTS "table_address_idx" ON "table" ("address")`,
+		`ALTER TABLE "table" ADD COLUMN IF NOT EXISTS "owner" VARCHAR(24)`,
+		`CREATE INDEX IF NOT EXISTS "table_owner_idx" ON "table" ("owner")`,
+	}
+	if !reflect.DeepEqual(queries, expectedQueries) {
+		t.Errorf("Expected queries: %#v, got: %#v", expectedQueries, queries)
+	}
+}
+
+func TestStore_buildMigrateQueries_point(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"id":   pgsql.IDField,
+			"home": {Validator: pgtypes.Point{}},
+		},
+	}
+	s := newTestStore()
+	s.schema = sc
+	s.columns = promotedFields(sc, []string{"id", "home"})
+
+	queries, err := s.buildMigrateQueries(sc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedQueries := []string{
+		`CREATE TABLE IF NOT EXISTS "table" (id VARCHAR(24),etag VARCHAR(32),updated TIMESTAMP,payload JSONB,"home" POINT,PRIMARY KEY(id))`,
+		`ALTER TABLE "table" ADD COLUMN IF NOT EXISTS "home" POINT`,
+	}
+	if !reflect.DeepEqual(queries, expectedQueries) {
+		t.Errorf("Expected queries: %#v, got: %#v", expectedQueries, queries)
+	}
+
+	want := `CREATE INDEX IF NOT EXISTS "table_home_idx" ON "table" USING GIST ("home")`
+	if got := internal.PointIndexQueries(s.table, sc, s.pointText); !reflect.DeepEqual(got, []string{want}) {
+		t.Errorf("PointIndexQueries() = %#v, want %s", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package hybrid
+
+import (
+	"encoding/json"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// splitPayload splits the payload of an item between the promoted columns
+// and the payload column. The id is left out of both.
+func (s store) splitPayload(payload map[string]any) (columns, rest map[string]any) {
+	columns = map[string]any{}
+	rest = map[string]any{}
+	for name, value := range payload {
+		switch _, promoted := s.columns[name]; {
+		case name == "id":
+		case promoted:
+			columns[name] = value
+		default:
+			rest[name] = value
+		}
+	}
+	return columns, rest
+}
+
+// columnValue returns the value stored in the column of a promoted field.
+func (s store) columnValue(name string, value any) (any, error) {
+	if value == nil || !isJSONField(s.columns[name]) {
+		return value, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// encodePayload returns the value of the payload column.
+func encodePayload(rest map[string]any) (string, error) {
+	b, err := json.Marshal(rest)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func isJSONField(field schema.Field) bool {
+	switch field.Validator.(type) {
+	case *schema.Object, *schema.Array, *schema.Dict:
+		return true
+	case nil:
+		return field.Schema != nil
+	}
+	return false
+}
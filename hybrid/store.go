@@ -0,0 +1,85 @@
+// Package hybrid stores items in a table where selected fields are promoted
+// to typed columns, for relational performance on the fields most filtered
+// and sorted on, and the other fields live in a JSONB payload column.
+package hybrid
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/schema"
+
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+type PostgresStorer interface {
+	resource.Storer
+	AutoMigrate() error
+}
+
+type store struct {
+	table   string
+	dialect goqu.DialectWrapper
+	schema  *schema.Schema
+	columns schema.Fields
+	opts    *pgsql.Options
+	exec    *internal.Executor
+}
+
+// NewStore returns a store promoting the top level fields named in columns
+// to columns of their own. The id is always a column; names not in the
+// schema are ignored.
+func NewStore(table string, db *sql.DB, sc *schema.Schema, columns []string, opts ...pgsql.Option) PostgresStorer {
+	o := pgsql.NewOptions(opts...)
+	s := &store{
+		table:   table,
+		dialect: goqu.Dialect("postgres"),
+		schema:  sc,
+		columns: promotedFields(sc, columns),
+		opts:    o,
+		exec:    internal.NewExecutor(table, db, o),
+	}
+
+	return s
+}
+
+// NewTenantStore returns a store running its statements on the database
+// returned by resolve for each request, e.g. a pgsql.TenantPools. Every
+// database is migrated on first use, so AutoMigrate is not needed.
+func NewTenantStore(table string, resolve pgsql.DBResolver, sc *schema.Schema, columns []string, opts ...pgsql.Option) PostgresStorer {
+	o := pgsql.NewOptions(opts...)
+	s := &store{
+		table:   table,
+		dialect: goqu.Dialect("postgres"),
+		schema:  sc,
+		columns: promotedFields(sc, columns),
+		opts:    o,
+		exec:    &internal.Executor{Table: table, Resolve: resolve, Options: o},
+	}
+	s.exec.Migrate = func(ctx context.Context, db *sql.DB) error {
+		return s.migrate(ctx, db, s.schema)
+	}
+
+	return s
+}
+
+func promotedFields(sc *schema.Schema, columns []string) schema.Fields {
+	fields := schema.Fields{}
+	for _, name := range columns {
+		if field, ok := sc.Fields[name]; ok && name != "id" {
+			if _, ok := field.Validator.(*pgsql.Search); !ok {
+				fields[name] = field
+			}
+		}
+	}
+	return fields
+}
+
+func (s *store) AutoMigrate() error {
+	return s.Migrate(context.TODO(), s.schema)
+}
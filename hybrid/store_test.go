@@ -0,0 +1,40 @@
+package hybrid
+
+import (
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+var testSchema = &schema.Schema{
+	Fields: schema.Fields{
+		"id":    pgsql.IDField,
+		"owner": {Validator: &schema.String{MaxLen: 24}, Required: true},
+		"age":   {Validator: &schema.Integer{}},
+		"name":  {Validator: &schema.String{}},
+		"tags": {
+			Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.String{}},
+			},
+		},
+		"address": {
+			Validator: &schema.Object{Schema: &schema.Schema{
+				Fields: schema.Fields{
+					"city": {Validator: &schema.String{}},
+					"zip":  {Validator: &schema.Integer{}},
+				},
+			}},
+		},
+	},
+}
+
+func newTestStore() store {
+	return store{
+		table:   "table",
+		dialect: goqu.Dialect("postgres"),
+		schema:  testSchema,
+		columns: promotedFields(testSchema, []string{"id", "owner", "address", "unknown"}),
+		opts:    pgsql.NewOptions(),
+	}
+}
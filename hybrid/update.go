@@ -0,0 +1,77 @@
+package hybrid
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Update(ctx context.Context, item *resource.Item, original *resource.Item) (err error) {
+	defer internal.Measure(s.opts, s.table, "Update", time.Now(), &err)
+
+	sqlStr, args, err := s.buildUpdateQuery(item, original)
+	if err != nil {
+		return err
+	}
+
+	return s.exec.RunWrite(ctx, "Update", func(ctx context.Context, q internal.Querier) error {
+		affect, err := q.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := affect.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return internal.Conflict(ctx, q, s.table, "etag", item.ID, internal.Live(s.opts)...)
+		}
+
+		return internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationUpdate, original, item)
+	})
+}
+
+// buildUpdateQuery only sets the promoted columns that changed, and the
+// payload column when one of its fields changed.
+func (s store) buildUpdateQuery(i *resource.Item, o *resource.Item) (string, []any, error) {
+	columns, rest := s.splitPayload(internal.UTCPayload(s.opts, i.Payload))
+	oldColumns, oldRest := s.splitPayload(internal.UTCPayload(s.opts, o.Payload))
+
+	record := goqu.Record{
+		"etag":    i.ETag,
+		"updated": i.Updated,
+	}
+	for name := range s.columns {
+		value, ok := columns[name]
+		oldValue, oldOk := oldColumns[name]
+		if ok == oldOk && reflect.DeepEqual(value, oldValue) {
+			continue
+		}
+		var err error
+		if record[name], err = s.columnValue(name, value); err != nil {
+			return "", nil, err
+		}
+	}
+	if !reflect.DeepEqual(rest, oldRest) {
+		payload, err := encodePayload(rest)
+		if err != nil {
+			return "", nil, err
+		}
+		record["payload"] = payload
+	}
+
+	builder := s.dialect.Update(s.table).Set(record).Where(goqu.C("etag").Eq(o.ETag), goqu.C("id").Eq(i.ID))
+	if s.opts.SoftDelete {
+		builder = builder.Where(goqu.C(pgsql.DeletedColumn).IsNull())
+	}
+
+	return builder.Prepared(true).ToSQL()
+}
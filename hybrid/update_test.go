@@ -0,0 +1,79 @@
+package hybrid
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/rest-layer/resource"
+)
+
+func TestStore_buildUpdateQuery(t *testing.T) {
+	s := newTestStore()
+	updated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	original := &resource.Item{
+		ID:   "1",
+		ETag: "old",
+		Payload: map[string]any{
+			"id":      "1",
+			"owner":   "alice",
+			"name":    "John",
+			"address": map[string]any{"city": "Sofia"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		payload      map[string]any
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			name: "column",
+			payload: map[string]any{
+				"id":      "1",
+				"owner":   "bob",
+				"name":    "John",
+				"address": map[string]any{"city": "Sofia"},
+			},
+			expectedSQL:  `UPDATE "table" SET "etag"=$1,"owner"=$2,"updated"=$3 WHERE (("etag" = $4) AND ("id" = $5))`,
+			expectedArgs: []any{"new", "bob", updated, "old", "1"},
+		},
+		{
+			name: "payload",
+			payload: map[string]any{
+				"id":      "1",
+				"owner":   "alice",
+				"name":    "Jane",
+				"address": map[string]any{"city": "Sofia"},
+			},
+			expectedSQL:  `UPDATE "table" SET "etag"=$1,"payload"=$2,"updated"=$3 WHERE (("etag" = $4) AND ("id" = $5))`,
+			expectedArgs: []any{"new", `{"name":"Jane"}`, updated, "old", "1"},
+		},
+		{
+			name: "JSONB column removed",
+			payload: map[string]any{
+				"id":    "1",
+				"owner": "alice",
+				"name":  "John",
+			},
+			expectedSQL:  `UPDATE "table" SET "address"=$1,"etag"=$2,"updated"=$3 WHERE (("etag" = $4) AND ("id" = $5))`,
+			expectedArgs: []any{nil, "new", updated, "old", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &resource.Item{ID: "1", ETag: "new", Updated: updated, Payload: tt.payload}
+			sqlStr, args, err := s.buildUpdateQuery(item, original)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sqlStr != tt.expectedSQL {
+				t.Errorf("Expected query: %s, got: %s", tt.expectedSQL, sqlStr)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("Expected args: %#v, got: %#v", tt.expectedArgs, args)
+			}
+		})
+	}
+}
@@ -2,6 +2,15 @@ package pgsql
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/rest-layer/schema"
 	"github.com/rs/xid"
@@ -45,9 +54,7 @@ var (
 		Filterable:  true,
 		Sortable:    true,
 		Default:     "0",
-		Validator: &schema.String{
-			Regexp: "^[0-9]{1,20}$",
-		},
+		Validator:   Serial{},
 	}
 )
 
@@ -56,3 +63,337 @@ var (
 func newID() string {
 	return xid.New().String()
 }
+
+// IDStrategy decides the type of the id column of a store and how the ids
+// of its items are generated. The stores use the strategy set by
+// WithIDStrategy, else the validator of the id field when it is one, else
+// XID.
+type IDStrategy interface {
+	schema.FieldValidator
+	// ColumnType is the type of the id column, e.g. UUID.
+	ColumnType() string
+	// Generated tells if the database generates the ids, in which case
+	// inserts leave them out and read them back.
+	Generated() bool
+	// NewID returns a new id, when the application generates them.
+	NewID() string
+	// ParseID returns the id of an item from the value of its id column.
+	ParseID(value interface{}) (interface{}, error)
+}
+
+// WithIDStrategy makes the store use ids of the given strategy, whatever the
+// validator of the id field.
+func WithIDStrategy(ids IDStrategy) Option {
+	return func(o *Options) {
+		o.IDStrategy = ids
+	}
+}
+
+// NewIDField returns the configuration of an id field of the given strategy.
+// The application generated ids are set by its OnInit hook; the database
+// generated ones are "0" until the item is inserted.
+func NewIDField(ids IDStrategy) schema.Field {
+	field := schema.Field{
+		Description: "The item's id",
+		Required:    true,
+		ReadOnly:    true,
+		Filterable:  true,
+		Sortable:    true,
+		Validator:   ids,
+	}
+	if ids.Generated() {
+		field.Default = "0"
+	} else {
+		field.OnInit = func(ctx context.Context, value interface{}) interface{} {
+			if value == nil {
+				value = ids.NewID()
+			}
+			return value
+		}
+	}
+	return field
+}
+
+var (
+	xidExpr    = regexp.MustCompile(`^[0-9a-v]{20}$`)
+	uuidExpr   = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	serialExpr = regexp.MustCompile(`^[0-9]{1,20}$`)
+)
+
+// XID is the IDStrategy of the xids generated by IDField, stored as
+// VARCHAR(24) as the stores always did.
+type XID struct{}
+
+// Validate implements schema.FieldValidator.
+func (XID) Validate(value interface{}) (interface{}, error) {
+	return validateID(value, xidExpr, "not an xid")
+}
+
+// ColumnType implements IDStrategy.
+func (XID) ColumnType() string { return "VARCHAR(24)" }
+
+// Generated implements IDStrategy.
+func (XID) Generated() bool { return false }
+
+// NewID implements IDStrategy.
+func (XID) NewID() string { return newID() }
+
+// ParseID implements IDStrategy.
+func (XID) ParseID(value interface{}) (interface{}, error) { return parseTextID(value) }
+
+// LessFunc implements schema.FieldComparator.
+func (XID) LessFunc() schema.LessFunc { return lessTextID }
+
+// UUIDv4 is the IDStrategy of random UUIDs, stored as UUID.
+type UUIDv4 struct{}
+
+// Validate implements schema.FieldValidator.
+func (UUIDv4) Validate(value interface{}) (interface{}, error) {
+	return validateUUID(value)
+}
+
+// ColumnType implements IDStrategy.
+func (UUIDv4) ColumnType() string { return "UUID" }
+
+// Generated implements IDStrategy.
+func (UUIDv4) Generated() bool { return false }
+
+// NewID implements IDStrategy.
+func (UUIDv4) NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return formatUUID(b, 4)
+}
+
+// ParseID implements IDStrategy.
+func (UUIDv4) ParseID(value interface{}) (interface{}, error) { return parseTextID(value) }
+
+// LessFunc implements schema.FieldComparator.
+func (UUIDv4) LessFunc() schema.LessFunc { return lessTextID }
+
+// UUIDv7 is the IDStrategy of time ordered UUIDs, stored as UUID. Their
+// order follows the creation of the items, which keeps the index of the id
+// column compact.
+type UUIDv7 struct{}
+
+// Validate implements schema.FieldValidator.
+func (UUIDv7) Validate(value interface{}) (interface{}, error) {
+	return validateUUID(value)
+}
+
+// ColumnType implements IDStrategy.
+func (UUIDv7) ColumnType() string { return "UUID" }
+
+// Generated implements IDStrategy.
+func (UUIDv7) Generated() bool { return false }
+
+// NewID implements IDStrategy.
+func (UUIDv7) NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic(err)
+	}
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	return formatUUID(b, 7)
+}
+
+// ParseID implements IDStrategy.
+func (UUIDv7) ParseID(value interface{}) (interface{}, error) { return parseTextID(value) }
+
+// LessFunc implements schema.FieldComparator.
+func (UUIDv7) LessFunc() schema.LessFunc { return lessTextID }
+
+// Serial is the IDStrategy of SerialID, whose ids are generated by a SERIAL
+// column.
+type Serial struct{}
+
+// Validate implements schema.FieldValidator.
+func (Serial) Validate(value interface{}) (interface{}, error) {
+	return validateID(value, serialExpr, "not a serial id")
+}
+
+// ColumnType implements IDStrategy.
+func (Serial) ColumnType() string { return "SERIAL" }
+
+// Generated implements IDStrategy.
+func (Serial) Generated() bool { return true }
+
+// NewID implements IDStrategy.
+func (Serial) NewID() string { return "0" }
+
+// ParseID implements IDStrategy.
+func (Serial) ParseID(value interface{}) (interface{}, error) { return parseIntegerID(value) }
+
+// LessFunc implements schema.FieldComparator.
+func (Serial) LessFunc() schema.LessFunc { return lessIntegerID }
+
+// Identity is the IDStrategy of ids generated by a BIGINT GENERATED ALWAYS
+// AS IDENTITY column, the standard replacement of SERIAL.
+type Identity struct{}
+
+// Validate implements schema.FieldValidator.
+func (Identity) Validate(value interface{}) (interface{}, error) {
+	return validateID(value, serialExpr, "not an identity id")
+}
+
+// ColumnType implements IDStrategy.
+func (Identity) ColumnType() string { return "BIGINT GENERATED ALWAYS AS IDENTITY" }
+
+// Generated implements IDStrategy.
+func (Identity) Generated() bool { return true }
+
+// NewID implements IDStrategy.
+func (Identity) NewID() string { return "0" }
+
+// ParseID implements IDStrategy.
+func (Identity) ParseID(value interface{}) (interface{}, error) { return parseIntegerID(value) }
+
+// LessFunc implements schema.FieldComparator.
+func (Identity) LessFunc() schema.LessFunc { return lessIntegerID }
+
+// SnowflakeEpoch is the default epoch of Snowflake ids.
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake is the IDStrategy of time ordered 63 bits integers generated by
+// the application, stored as BIGINT: 41 bits of milliseconds since the
+// epoch, 10 bits of node and 12 bits of sequence. Every process generating
+// ids for the same table needs a node of its own.
+type Snowflake struct {
+	// Node is the node of the ids, from 0 to 1023.
+	Node int64
+	// Epoch is the start of the time of the ids, SnowflakeEpoch when zero.
+	Epoch time.Time
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake returns a Snowflake strategy generating ids for node.
+func NewSnowflake(node int64) *Snowflake {
+	return &Snowflake{Node: node}
+}
+
+// Validate implements schema.FieldValidator.
+func (s *Snowflake) Validate(value interface{}) (interface{}, error) {
+	return validateID(value, serialExpr, "not a snowflake id")
+}
+
+// ColumnType implements IDStrategy.
+func (s *Snowflake) ColumnType() string { return "BIGINT" }
+
+// Generated implements IDStrategy.
+func (s *Snowflake) Generated() bool { return false }
+
+// NewID implements IDStrategy. It waits for the next millisecond when 4096
+// ids were generated in the current one.
+func (s *Snowflake) NewID() string {
+	epoch := s.Epoch
+	if epoch.IsZero() {
+		epoch = SnowflakeEpoch
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Since(epoch).Milliseconds()
+	if now < s.last {
+		// The clock went back, keep the ids ordered
+		now = s.last
+	}
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & 0xfff
+		if s.sequence == 0 {
+			for now <= s.last {
+				time.Sleep(time.Millisecond / 10)
+				now = time.Since(epoch).Milliseconds()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = now
+
+	return strconv.FormatInt(now<<22|(s.Node&0x3ff)<<12|s.sequence, 10)
+}
+
+// ParseID implements IDStrategy.
+func (s *Snowflake) ParseID(value interface{}) (interface{}, error) { return parseIntegerID(value) }
+
+// LessFunc implements schema.FieldComparator.
+func (s *Snowflake) LessFunc() schema.LessFunc { return lessIntegerID }
+
+func validateID(value interface{}, expr *regexp.Regexp, message string) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	if !expr.MatchString(s) {
+		return nil, errors.New(message)
+	}
+	return s, nil
+}
+
+func validateUUID(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return validateID(strings.ToLower(s), uuidExpr, "not a UUID")
+}
+
+// formatUUID returns the text of the UUID b of the given version, of the
+// RFC 4122 variant.
+func formatUUID(b [16]byte, version byte) string {
+	b[6] = b[6]&0x0f | version<<4
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func parseTextID(value interface{}) (interface{}, error) {
+	switch t := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	}
+	return nil, fmt.Errorf("unexpected id %v", value)
+}
+
+func parseIntegerID(value interface{}) (interface{}, error) {
+	switch t := value.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	}
+	return nil, fmt.Errorf("unexpected id %v", value)
+}
+
+func lessTextID(value, other interface{}) bool {
+	s1, ok1 := value.(string)
+	s2, ok2 := other.(string)
+	return ok1 && ok2 && s1 < s2
+}
+
+func lessIntegerID(value, other interface{}) bool {
+	s1, ok1 := value.(string)
+	s2, ok2 := other.(string)
+	if !ok1 || !ok2 {
+		return false
+	}
+	i1, err1 := strconv.ParseInt(s1, 10, 64)
+	i2, err2 := strconv.ParseInt(s2, 10, 64)
+	return err1 == nil && err2 == nil && i1 < i2
+}
@@ -0,0 +1,86 @@
+package pgsql
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIDStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        IDStrategy
+		columnType string
+		generated  bool
+		pattern    string
+	}{
+		{"xid", XID{}, "VARCHAR(24)", false, `^[0-9a-v]{20}$`},
+		{"uuidv4", UUIDv4{}, "UUID", false, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"uuidv7", UUIDv7{}, "UUID", false, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"serial", Serial{}, "SERIAL", true, `^0$`},
+		{"identity", Identity{}, "BIGINT GENERATED ALWAYS AS IDENTITY", true, `^0$`},
+		{"snowflake", NewSnowflake(1), "BIGINT", false, `^[0-9]{1,19}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ids.ColumnType(); got != tt.columnType {
+				t.Errorf("ColumnType() = %s, want %s", got, tt.columnType)
+			}
+			if got := tt.ids.Generated(); got != tt.generated {
+				t.Errorf("Generated() = %v, want %v", got, tt.generated)
+			}
+
+			field := NewIDField(tt.ids)
+			id, err := field.Validator.Validate(field.Default)
+			if !tt.generated {
+				id, err = field.Validator.Validate(field.OnInit(context.Background(), nil))
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(id.(string)) {
+				t.Errorf("ID = %s, want to match %s", id, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestIDStrategy_ParseID(t *testing.T) {
+	if id, err := (Serial{}).ParseID(int64(42)); err != nil || id != "42" {
+		t.Errorf("ParseID() = %v, %v, want 42", id, err)
+	}
+	if id, err := (UUIDv4{}).ParseID([]byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")); err != nil || id != "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11" {
+		t.Errorf("ParseID() = %v, %v", id, err)
+	}
+	if _, err := (XID{}).ParseID(42); err == nil {
+		t.Error("ParseID() expected an error")
+	}
+	if _, err := (UUIDv4{}).Validate("not-a-uuid"); err == nil {
+		t.Error("Validate() expected an error")
+	}
+}
+
+func TestSnowflake_NewID(t *testing.T) {
+	s := &Snowflake{Node: 5, Epoch: time.Now().Add(-time.Hour)}
+	last := int64(-1)
+	for i := 0; i < 5000; i++ {
+		id, err := strconv.ParseInt(s.NewID(), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("NewID() = %d after %d, want increasing ids", id, last)
+		}
+		if node := id >> 12 & 0x3ff; node != 5 {
+			t.Fatalf("Node = %d, want 5", node)
+		}
+		last = id
+	}
+
+	less := s.LessFunc()
+	if !less("9", "10") || less("10", "9") {
+		t.Error("LessFunc() does not compare numerically")
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// PrepareAggregate makes builder, already filtering the items, select the
+// groups and aggregations of spec, sorted and windowed by q. typed returns the
+// expression of a field, cast to the type of its values. The group fields are
+// selected as group_<i> and the aggregations as value_<i>.
+func PrepareAggregate(builder *goqu.SelectDataset, sc *schema.Schema, q *query.Query, spec pgsql.AggregateSpec, typed func(field string) exp.Expression) error {
+	if err := spec.Validate(sc); err != nil {
+		return err
+	}
+
+	var selects []any
+	groups := map[string]exp.Expression{}
+	var groupBy []any
+	for i, field := range spec.GroupBy {
+		name := fmt.Sprintf("group_%d", i)
+		groups[field] = goqu.C(name)
+		// Grouping by position, the placeholders of the selected expression
+		// would not match the ones of a repeated one
+		groupBy = append(groupBy, goqu.L(strconv.Itoa(i+1)))
+		selects = append(selects, goqu.L("?", typed(field)).As(name))
+	}
+
+	values := map[string]exp.Expression{}
+	for i, a := range spec.Aggregations {
+		name := fmt.Sprintf("value_%d", i)
+		values[a.Name] = goqu.C(name)
+		selects = append(selects, aggregation(sc, a, typed).As(name))
+	}
+
+	*builder = *builder.Select(selects...)
+	if len(groupBy) > 0 {
+		*builder = *builder.GroupBy(groupBy...)
+	}
+
+	for _, sort := range q.Sort {
+		expr, ok := groups[sort.Name]
+		if !ok {
+			if expr, ok = values[sort.Name]; !ok {
+				return fmt.Errorf("aggregate: cannot sort by %s", sort.Name)
+			}
+		}
+		orderable := goqu.L("?", expr)
+		if sort.Reversed {
+			*builder = *builder.OrderAppend(orderable.Desc())
+		} else {
+			*builder = *builder.OrderAppend(orderable.Asc())
+		}
+	}
+
+	if q.Window != nil {
+		if q.Window.Limit >= 0 {
+			*builder = *builder.Limit(uint(q.Window.Limit))
+		}
+		if q.Window.Offset > 0 {
+			*builder = *builder.Offset(uint(q.Window.Offset))
+		}
+	}
+	return nil
+}
+
+// aggregation returns the expression of a, cast to the type of its values in
+// AggregateRow.
+func aggregation(sc *schema.Schema, a pgsql.Aggregation, typed func(field string) exp.Expression) exp.Aliaseable {
+	if a.Field == "" {
+		return goqu.COUNT(goqu.Star())
+	}
+
+	field := typed(a.Field)
+	switch a.Func {
+	case pgsql.AggregateCount:
+		return goqu.COUNT(field)
+	case pgsql.AggregateSum:
+		if _, ok := sc.GetField(a.Field).Validator.(*schema.Integer); ok {
+			return goqu.Cast(goqu.SUM(field), "BIGINT")
+		}
+		return goqu.Cast(goqu.SUM(field), "DOUBLE PRECISION")
+	case pgsql.AggregateAvg:
+		return goqu.Cast(goqu.AVG(field), "DOUBLE PRECISION")
+	case pgsql.AggregateMin:
+		return goqu.MIN(field)
+	default:
+		return goqu.MAX(field)
+	}
+}
+
+// ScanAggregate reads the rows selected by PrepareAggregate.
+func ScanAggregate(rows *sql.Rows, sc *schema.Schema, spec pgsql.AggregateSpec) ([]pgsql.AggregateRow, error) {
+	var result []pgsql.AggregateRow
+	for rows.Next() {
+		vals := make([]any, len(spec.GroupBy)+len(spec.Aggregations))
+		ptrs := make([]any, len(vals))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := pgsql.AggregateRow{
+			Group:  make(map[string]any, len(spec.GroupBy)),
+			Values: make(map[string]any, len(spec.Aggregations)),
+		}
+		for i, field := range spec.GroupBy {
+			row.Group[field] = aggregateValue(sc.GetField(field), vals[i])
+		}
+		for i, a := range spec.Aggregations {
+			var field *schema.Field
+			if a.Func == pgsql.AggregateMin || a.Func == pgsql.AggregateMax {
+				field = sc.GetField(a.Field)
+			}
+			row.Values[a.Name] = aggregateValue(field, vals[len(spec.GroupBy)+i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// aggregateValue converts the texts scanned for field, e.g. of NUMERIC
+// columns, to strings, or to float64 for float fields.
+func aggregateValue(field *schema.Field, v any) any {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	if field != nil {
+		if _, ok := field.Validator.(*schema.Float); ok {
+			if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+				return f
+			}
+		}
+	}
+	return string(b)
+}
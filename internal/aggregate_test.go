@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestPrepareAggregate(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"status": {Validator: &schema.String{}, Filterable: true},
+			"amount": {Validator: &schema.Integer{}},
+			"price":  {Validator: &schema.Float{}},
+			"address": {Validator: &schema.Object{Schema: &schema.Schema{
+				Fields: schema.Fields{
+					"city": {Validator: &schema.String{}, Filterable: true},
+				},
+			}}},
+		},
+	}
+	typed := func(field string) exp.Expression {
+		expr := goqu.L("payload->>?", field)
+		if pgType := CastType(sc, field, &pgsql.Options{}); pgType != "" {
+			return goqu.Cast(expr, pgType)
+		}
+		return expr
+	}
+
+	tests := []struct {
+		name       string
+		query      query.Query
+		spec       pgsql.AggregateSpec
+		wantSQL    string
+		wantArgs   []any
+		wantErrStr string
+	}{
+		{
+			name: "count",
+			spec: pgsql.AggregateSpec{
+				Aggregations: []pgsql.Aggregation{{Name: "n", Func: pgsql.AggregateCount}},
+			},
+			wantSQL: `SELECT COUNT(*) AS "value_0" FROM "table"`,
+		},
+		{
+			name: "group",
+			query: query.Query{
+				Sort:   query.Sort{{Name: "total", Reversed: true}, {Name: "address.city"}},
+				Window: &query.Window{Limit: 10, Offset: 20},
+			},
+			spec: pgsql.AggregateSpec{
+				GroupBy: []string{"status", "address.city"},
+				Aggregations: []pgsql.Aggregation{
+					{Name: "total", Func: pgsql.AggregateSum, Field: "amount"},
+					{Name: "mean", Func: pgsql.AggregateAvg, Field: "price"},
+					{Name: "top", Func: pgsql.AggregateMax, Field: "price"},
+				},
+			},
+			wantSQL: `SELECT payload->>$1 AS "group_0", payload->>$2 AS "group_1", ` +
+				`CAST(SUM(CAST(payload->>$3 AS INTEGER)) AS BIGINT) AS "value_0", ` +
+				`CAST(AVG(CAST(payload->>$4 AS DOUBLE PRECISION)) AS DOUBLE PRECISION) AS "value_1", ` +
+				`MAX(CAST(payload->>$5 AS DOUBLE PRECISION)) AS "value_2" FROM "table" ` +
+				`GROUP BY 1, 2 ORDER BY "value_0" DESC, "group_1" ASC LIMIT $6 OFFSET $7`,
+			wantArgs: []any{"status", "address.city", "amount", "price", "price", int64(10), int64(20)},
+		},
+		{
+			name:  "unknown sort",
+			query: query.Query{Sort: query.Sort{{Name: "price"}}},
+			spec: pgsql.AggregateSpec{
+				Aggregations: []pgsql.Aggregation{{Name: "n", Func: pgsql.AggregateCount}},
+			},
+			wantErrStr: "aggregate: cannot sort by price",
+		},
+		{
+			name: "unknown function",
+			spec: pgsql.AggregateSpec{
+				Aggregations: []pgsql.Aggregation{{Name: "n", Func: "median", Field: "price"}},
+			},
+			wantErrStr: "aggregate: n: unknown function median",
+		},
+		{
+			name: "missing field",
+			spec: pgsql.AggregateSpec{
+				Aggregations: []pgsql.Aggregation{{Name: "n", Func: pgsql.AggregateSum}},
+			},
+			wantErrStr: "aggregate: n: sum needs a field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := goqu.Dialect("postgres").From("table")
+			err := PrepareAggregate(builder, sc, &tt.query, tt.spec, typed)
+			if tt.wantErrStr != "" {
+				if err == nil || err.Error() != tt.wantErrStr {
+					t.Fatalf("Error = %v, want %s", err, tt.wantErrStr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			sql, args, err := builder.Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if len(args) > 0 || len(tt.wantArgs) > 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// ArrayPredicate returns the expression of e when it applies to an array
+// field of sc: an Equal, NotEqual, In or NotIn, with the semantics documented
+// by pgsql, or one of the pgsql array predicates, which only apply to arrays.
+// array returns the JSONB value of a field. ok is false for other
+// expressions.
+func ArrayPredicate(sc *schema.Schema, e query.Expression, array func(field string) exp.Expression) (expr exp.Expression, ok bool, err error) {
+	field, ok := ArrayPredicateField(e)
+	if !ok {
+		return nil, false, nil
+	}
+
+	isArray := false
+	if sc != nil {
+		if f := sc.GetField(field); f != nil {
+			_, isArray = f.Validator.(*schema.Array)
+		}
+	}
+	if !isArray {
+		switch e.(type) {
+		case *pgsql.ContainsAll, *pgsql.ContainsAny, *pgsql.Size:
+			return nil, true, fmt.Errorf("%s: not an array", field)
+		}
+		return nil, false, nil
+	}
+
+	expr, err = arrayPredicate(array(field), e)
+	return expr, true, err
+}
+
+// ArrayPredicateField returns the field of e when it may be an array
+// predicate.
+func ArrayPredicateField(e query.Expression) (string, bool) {
+	switch t := e.(type) {
+	case *query.Equal:
+		return t.Field, true
+	case *query.NotEqual:
+		return t.Field, true
+	case *query.In:
+		return t.Field, true
+	case *query.NotIn:
+		return t.Field, true
+	case *pgsql.ContainsAll:
+		return t.Field, true
+	case *pgsql.ContainsAny:
+		return t.Field, true
+	case *pgsql.Size:
+		return t.Field, true
+	}
+	return "", false
+}
+
+func arrayPredicate(array exp.Expression, e query.Expression) (exp.Expression, error) {
+	switch t := e.(type) {
+	case *query.Equal:
+		return containsAll(array, elements(t.Value))
+	case *query.NotEqual:
+		expr, err := containsAll(array, elements(t.Value))
+		return negate(expr), err
+	case *query.In:
+		return containsAny(array, t.Values)
+	case *query.NotIn:
+		expr, err := containsAny(array, t.Values)
+		return negate(expr), err
+	case *pgsql.ContainsAll:
+		return containsAll(array, t.Values)
+	case *pgsql.ContainsAny:
+		values := make([]any, len(t.Values))
+		for i, v := range t.Values {
+			values[i] = []any{v}
+		}
+		return containsAny(array, values)
+	case *pgsql.Size:
+		switch t.Op {
+		case pgsql.SizeEqual, pgsql.SizeNotEqual, pgsql.SizeLowerThan, pgsql.SizeLowerOrEqual, pgsql.SizeGreaterThan, pgsql.SizeGreaterOrEqual:
+		default:
+			return nil, fmt.Errorf("%s: invalid size comparison %q", t.Field, t.Op)
+		}
+		return goqu.L("jsonb_array_length(CASE WHEN jsonb_typeof(?) = 'array' THEN ? END) "+t.Op+" ?", array, array, t.Value), nil
+	}
+	return nil, fmt.Errorf("unsupported array predicate %T", e)
+}
+
+// NativeArrayPredicate returns the expression of e on column, a native array
+// column: an Equal, NotEqual, In or NotIn, with the semantics documented by
+// pgsql, or one of the pgsql array predicates. ok is false for other
+// expressions.
+func NativeArrayPredicate(e query.Expression, column exp.Expression) (expr exp.Expression, ok bool, err error) {
+	switch t := e.(type) {
+	case *query.Equal:
+		return nativeContains(column, t.Value), true, nil
+	case *query.NotEqual:
+		return negate(nativeContains(column, t.Value)), true, nil
+	case *query.In:
+		return nativeContainsAny(column, t.Values), true, nil
+	case *query.NotIn:
+		return negate(nativeContainsAny(column, t.Values)), true, nil
+	case *pgsql.ContainsAll:
+		return goqu.L("? @> ?", column, pq.Array(t.Values)), true, nil
+	case *pgsql.ContainsAny:
+		return goqu.L("? && ?", column, pq.Array(t.Values)), true, nil
+	case *pgsql.Size:
+		switch t.Op {
+		case pgsql.SizeEqual, pgsql.SizeNotEqual, pgsql.SizeLowerThan, pgsql.SizeLowerOrEqual, pgsql.SizeGreaterThan, pgsql.SizeGreaterOrEqual:
+		default:
+			return nil, true, fmt.Errorf("%s: invalid size comparison %q", t.Field, t.Op)
+		}
+		return goqu.L("cardinality(?) "+t.Op+" ?", column, t.Value), true, nil
+	}
+	return nil, false, nil
+}
+
+// nativeContains matches the arrays containing v, or all the values of v when
+// it is an array.
+func nativeContains(column exp.Expression, v any) exp.Expression {
+	values := elements(v)
+	if len(values) == 1 {
+		return goqu.L("? = ANY(?)", values[0], column)
+	}
+	return goqu.L("? @> ?", column, pq.Array(values))
+}
+
+// nativeContainsAny matches the arrays containing any of the scalar values,
+// or all the values of any of the arrays.
+func nativeContainsAny(column exp.Expression, values []any) exp.Expression {
+	var scalars []any
+	var exprs []exp.Expression
+	for _, v := range values {
+		if values := elements(v); len(values) != 1 {
+			exprs = append(exprs, goqu.L("? @> ?", column, pq.Array(values)))
+			continue
+		}
+		scalars = append(scalars, elements(v)[0])
+	}
+	if len(scalars) > 0 || len(exprs) == 0 {
+		exprs = append([]exp.Expression{goqu.L("? && ?", column, pq.Array(scalars))}, exprs...)
+	}
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return goqu.Or(exprs...)
+}
+
+// ParseNativeArray converts text, a native array column scanned as text, to
+// the values of array.
+func ParseNativeArray(array *schema.Array, text string) ([]any, error) {
+	var texts pq.StringArray
+	if err := texts.Scan(text); err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(texts))
+	for i, t := range texts {
+		var err error
+		switch array.Values.Validator.(type) {
+		case *schema.Integer:
+			values[i], err = strconv.ParseInt(t, 10, 64)
+		case *schema.Float:
+			values[i], err = strconv.ParseFloat(t, 64)
+		case *schema.Bool:
+			values[i], err = strconv.ParseBool(t)
+		case *schema.Time:
+			values[i], err = parseNativeTime(t)
+		default:
+			values[i] = t
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// nativeTimeLayouts are the layouts of the TIMESTAMP and TIMESTAMPTZ
+// elements of arrays.
+var nativeTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+}
+
+// parseNativeTime parses the text of a time element of an array, returning
+// it in UTC.
+func parseNativeTime(text string) (t time.Time, err error) {
+	for _, layout := range nativeTimeLayouts {
+		if t, err = time.Parse(layout, text); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return t, err
+}
+
+// containsAll matches the arrays containing all of values.
+func containsAll(array exp.Expression, values []any) (exp.Expression, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return goqu.L("? @> ?::jsonb", array, string(b)), nil
+}
+
+// containsAny matches the arrays containing all the elements of any of
+// values.
+func containsAny(array exp.Expression, values []any) (exp.Expression, error) {
+	arrays := make([]string, len(values))
+	for i, v := range values {
+		b, err := json.Marshal(elements(v))
+		if err != nil {
+			return nil, err
+		}
+		arrays[i] = string(b)
+	}
+	return goqu.L("? @> ANY(?::jsonb[])", array, pq.Array(arrays)), nil
+}
+
+// negate negates expr, matching the items without an array as well.
+func negate(expr exp.Expression) exp.Expression {
+	if expr == nil {
+		return nil
+	}
+	return goqu.L("NOT coalesce(?, false)", expr)
+}
+
+// elements returns the elements of v, a slice or a single element.
+func elements(v any) []any {
+	if values, ok := v.([]any); ok {
+		return values
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return values
+	}
+	return []any{v}
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestArrayPredicate(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"name": {Validator: &schema.String{}},
+			"tags": {Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.String{}},
+			}},
+		},
+	}
+	array := func(field string) exp.Expression {
+		return goqu.L("payload->?", field)
+	}
+
+	tests := []struct {
+		name     string
+		e        query.Expression
+		wantOK   bool
+		wantErr  bool
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:   "not an array",
+			e:      &query.Equal{Field: "name", Value: "red"},
+			wantOK: false,
+		},
+		{
+			name:   "not an array predicate",
+			e:      &query.Exist{Field: "tags"},
+			wantOK: false,
+		},
+		{
+			name:     "query.Equal: array",
+			e:        &query.Equal{Field: "tags", Value: []any{"red", "blue"}},
+			wantOK:   true,
+			wantSQL:  `SELECT * FROM "table" WHERE payload->$1 @> $2::jsonb`,
+			wantArgs: []any{"tags", `["red","blue"]`},
+		},
+		{
+			name:     "query.NotIn",
+			e:        &query.NotIn{Field: "tags", Values: []query.Value{"red", "blue"}},
+			wantOK:   true,
+			wantSQL:  `SELECT * FROM "table" WHERE NOT coalesce(payload->$1 @> ANY($2::jsonb[]), false)`,
+			wantArgs: []any{"tags", `{"[\"red\"]","[\"blue\"]"}`},
+		},
+		{
+			name:     "pgsql.ContainsAll",
+			e:        &pgsql.ContainsAll{Field: "tags", Values: []any{"red", "blue"}},
+			wantOK:   true,
+			wantSQL:  `SELECT * FROM "table" WHERE payload->$1 @> $2::jsonb`,
+			wantArgs: []any{"tags", `["red","blue"]`},
+		},
+		{
+			name:     "pgsql.Size",
+			e:        &pgsql.Size{Field: "tags", Op: pgsql.SizeLowerThan, Value: 3},
+			wantOK:   true,
+			wantSQL:  `SELECT * FROM "table" WHERE jsonb_array_length(CASE WHEN jsonb_typeof(payload->$1) = 'array' THEN payload->$2 END) < $3`,
+			wantArgs: []any{"tags", "tags", int64(3)},
+		},
+		{
+			name:    "pgsql.Size: invalid comparison",
+			e:       &pgsql.Size{Field: "tags", Op: "~", Value: 3},
+			wantOK:  true,
+			wantErr: true,
+		},
+		{
+			name:    "pgsql.ContainsAny: not an array",
+			e:       &pgsql.ContainsAny{Field: "name", Values: []any{"red"}},
+			wantOK:  true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, ok, err := ArrayPredicate(sc, tt.e, array)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ArrayPredicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ArrayPredicate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok || err != nil {
+				return
+			}
+
+			sql, args, err := goqu.Dialect("postgres").From("table").Where(expr).Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, arg := range args {
+				if v, ok := arg.(driver.Valuer); ok {
+					args[i], _ = v.Value()
+				}
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseNativeArray(t *testing.T) {
+	tests := []struct {
+		name  string
+		array *schema.Array
+		text  string
+		want  []any
+	}{
+		{
+			name:  "strings",
+			array: &schema.Array{Values: schema.Field{Validator: &schema.String{}}},
+			text:  `{red,"light blue"}`,
+			want:  []any{"red", "light blue"},
+		},
+		{
+			name:  "integers",
+			array: &schema.Array{Values: schema.Field{Validator: &schema.Integer{}}},
+			text:  `{1,2}`,
+			want:  []any{int64(1), int64(2)},
+		},
+		{
+			name:  "booleans",
+			array: &schema.Array{Values: schema.Field{Validator: &schema.Bool{}}},
+			text:  `{t,f}`,
+			want:  []any{true, false},
+		},
+		{
+			name:  "times",
+			array: &schema.Array{Values: schema.Field{Validator: &schema.Time{}}},
+			text:  `{"2020-01-02 03:04:05.5"}`,
+			want:  []any{time.Date(2020, 1, 2, 3, 4, 5, 500000000, time.UTC)},
+		},
+		{
+			name:  "times with time zones",
+			array: &schema.Array{Values: schema.Field{Validator: &schema.Time{}}},
+			text:  `{"2020-01-02 05:04:05+02","2020-01-02 08:34:05+05:30"}`,
+			want:  []any{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		{
+			name:  "empty",
+			array: &schema.Array{Values: schema.Field{Validator: &schema.Float{}}},
+			text:  `{}`,
+			want:  []any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNativeArray(tt.array, tt.text)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNativeArray() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// PrepareClear returns the statement of a Clear. removal is the DELETE (or
+// soft delete UPDATE) of the matching rows and must return all their
+// columns. When history is enabled, the removed rows are recorded with
+// payload, see WithDeleteHistory. The statement returns the id and etag of
+// every removed row.
+func PrepareClear(ctx context.Context, table string, opts *pgsql.Options, removal exp.AppendableExpression, etagColumn, updatedColumn string, payload exp.Expression) (string, []any, error) {
+	if opts.History {
+		return WithDeleteHistory(ctx, table, removal, etagColumn, updatedColumn, payload).
+			Returning(goqu.C("item_id"), goqu.C("old_etag")).
+			Prepared(true).ToSQL()
+	}
+
+	return goqu.Dialect("postgres").From("removed").
+		With("removed", removal).
+		Select(goqu.Cast(goqu.C("id"), "VARCHAR"), goqu.C(etagColumn)).
+		Prepared(true).ToSQL()
+}
+
+// ExecClear runs a statement prepared by PrepareClear and returns the
+// removals as changes of table.
+func ExecClear(ctx context.Context, q Querier, table, sqlStr string, args []any) ([]pgsql.Change, error) {
+	rows, err := q.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []pgsql.Change{}
+	for rows.Next() {
+		var id string
+		var etag sql.NullString
+		if err := rows.Scan(&id, &etag); err != nil {
+			return nil, err
+		}
+		changes = append(changes, pgsql.Change{Table: table, ID: id, ETag: etag.String, Operation: pgsql.OperationDelete})
+	}
+
+	return changes, rows.Err()
+}
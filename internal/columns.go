@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// ColumnType returns the type of the column storing field.
+func ColumnType(field *schema.Field, opts *pgsql.Options) (string, error) {
+	pgType := ""
+	switch f := field.Validator.(type) {
+	case *schema.String:
+		if f.MaxLen > 0 {
+			pgType = fmt.Sprintf("VARCHAR(%d)", f.MaxLen)
+		} else {
+			pgType = "VARCHAR"
+		}
+	case *schema.Integer:
+		pgType = getIntegerScale(f)
+	case *schema.Float:
+		pgType = "DOUBLE PRECISION"
+	case *schema.Bool:
+		pgType = "BOOLEAN"
+	case *schema.Time:
+		pgType = TimeType(opts)
+	case *schema.URL, *schema.IP, *schema.Password:
+		pgType = "VARCHAR"
+	case *schema.Reference:
+		field := f.GetField("id")
+		var err error
+		pgType, err = ColumnType(field, opts)
+		if err != nil {
+			// TODO: this is a hack to get around the fact that we don't have a way to get the type of a reference field
+			pgType = "VARCHAR"
+		}
+	case *schema.Object, *schema.Dict, *schema.Array:
+		pgType = "JSONB"
+	case pgsql.PostgresTyper:
+		pgType = f.PostgresType()
+	case nil:
+		return "", fmt.Errorf("validator required")
+	default:
+		return "", fmt.Errorf("unsupported field validator type: %+v", f)
+	}
+
+	if field.Required {
+		pgType += " NOT NULL"
+	}
+
+	return pgType, nil
+}
+
+func getIntegerScale(f *schema.Integer) string {
+	if f.Boundaries == nil {
+		return "INTEGER"
+	}
+	if f.Boundaries.Max == 0 || f.Boundaries.Max > 1<<31-1 {
+		return "BIGINT"
+	}
+	if f.Boundaries.Max > 1<<15-1 {
+		return "INTEGER"
+	}
+	return "SMALLINT"
+}
+
+// CastType returns the type the texts of field, read from a JSONB column, are
+// cast to for comparisons. It is empty for strings.
+func CastType(s *schema.Schema, field string, opts *pgsql.Options) string {
+	if s == nil {
+		return ""
+	}
+	f := s.GetField(field)
+	if f == nil {
+		return ""
+	}
+	return castType(f.Validator, opts)
+}
+
+func castType(validator schema.FieldValidator, opts *pgsql.Options) string {
+	switch validator.(type) {
+	case *schema.Integer, *schema.Float, *schema.Bool, *schema.Time, pgsql.PostgresTyper:
+		pgType, err := ColumnType(&schema.Field{Validator: validator}, opts)
+		if err != nil || strings.HasPrefix(pgType, "VARCHAR") {
+			return ""
+		}
+		return pgType
+	}
+	return ""
+}
+
+// NativeArrayType returns the type of the native array column storing the
+// values of array, e.g. TEXT[], or an empty string when its values are not
+// scalars.
+func NativeArrayType(array *schema.Array, opts *pgsql.Options) string {
+	switch v := array.Values.Validator.(type) {
+	case *schema.String:
+		return "TEXT[]"
+	case *schema.Integer:
+		return "BIGINT[]"
+	case *schema.Float, *schema.Bool, *schema.Time, pgsql.PostgresTyper:
+		pgType, err := ColumnType(&schema.Field{Validator: v}, opts)
+		if err != nil {
+			return ""
+		}
+		return pgType + "[]"
+	}
+	return ""
+}
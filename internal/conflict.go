@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// PrepareConflict returns the statement looking up the etag of the item id
+// of table, restricted by filters.
+func PrepareConflict(table, etagColumn string, id any, filters ...exp.Expression) (string, []any, error) {
+	return goqu.Dialect("postgres").From(table).
+		Select(goqu.C(etagColumn)).
+		Where(append([]exp.Expression{goqu.C("id").Eq(id)}, filters...)...).
+		Prepared(true).ToSQL()
+}
+
+// Conflict returns why a conditional write of the item id affected no row:
+// resource.ErrNotFound when the item does not exist, or a precondition
+// failure reporting its current etag.
+func Conflict(ctx context.Context, q Querier, table, etagColumn string, id any, filters ...exp.Expression) error {
+	sqlStr, args, err := PrepareConflict(table, etagColumn, id, filters...)
+	if err != nil {
+		return err
+	}
+
+	var etag sql.NullString
+	err = q.QueryRowContext(ctx, sqlStr, args...).Scan(&etag)
+	if errors.Is(err, sql.ErrNoRows) {
+		return resource.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return pgsql.NewPreconditionFailed(etag.String)
+}
+
+// Live returns the filters restricting writes to live items, the ones not
+// soft deleted.
+func Live(opts *pgsql.Options) []exp.Expression {
+	if !opts.SoftDelete {
+		return nil
+	}
+	return []exp.Expression{goqu.C(pgsql.DeletedColumn).IsNull()}
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestPrepareConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *pgsql.Options
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "hard delete",
+			opts:     pgsql.NewOptions(),
+			wantSQL:  `SELECT "etag" FROM "table" WHERE ("id" = $1)`,
+			wantArgs: []any{"1"},
+		},
+		{
+			name:     "soft delete",
+			opts:     pgsql.NewOptions(pgsql.WithSoftDelete()),
+			wantSQL:  `SELECT "etag" FROM "table" WHERE (("id" = $1) AND ("deleted_at" IS NULL))`,
+			wantArgs: []any{"1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlStr, args, err := PrepareConflict("table", "etag", "1", Live(tt.opts)...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sqlStr != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sqlStr, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
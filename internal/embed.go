@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// EmbeddedPrefix prefixes the name of the column holding the item embedded
+// for a reference field.
+const EmbeddedPrefix = "_embedded_"
+
+// embeddedSkipped are the bookkeeping columns of the layouts, left out of the
+// embedded items.
+var embeddedSkipped = []string{"payload", "etag", "updated", "_etag", "_updated", pgsql.DeletedColumn, pgsql.SearchColumn}
+
+// Embed joins to builder, selecting from table, the items referenced by the
+// top level reference fields of pj projected with children, when refs tells
+// where they are stored. An item is selected in the EmbeddedPrefix column of
+// the name of its field, as a JSONB object projected on the children of the
+// first projection of the field. reference returns the value of a reference
+// field of table.
+func Embed(builder *goqu.SelectDataset, table string, sc *schema.Schema, pj query.Projection, refs map[string]pgsql.EmbeddedReference, reference func(name string) exp.Expression) {
+	embedded := map[string]bool{}
+	for _, pf := range pj {
+		if len(pf.Children) == 0 || embedded[pf.Name] {
+			continue
+		}
+		field := sc.GetField(pf.Name)
+		if field == nil {
+			continue
+		}
+		validator, ok := field.Validator.(*schema.Reference)
+		if !ok {
+			continue
+		}
+		ref, ok := refs[validator.Path]
+		if !ok {
+			continue
+		}
+
+		if len(embedded) == 0 {
+			if cols := builder.GetClauses().Select().Columns(); len(cols) == 1 {
+				if star, ok := cols[0].(exp.LiteralExpression); ok && star.Literal() == "*" {
+					// The joined columns are not part of the item
+					*builder = *builder.Select(goqu.T(table).All())
+				}
+			}
+		}
+
+		embedded[pf.Name] = true
+		alias := EmbeddedPrefix + pf.Name
+		*builder = *builder.
+			LeftJoin(goqu.Lateral(embeddedItem(ref, pf.Children, reference(pf.Name), alias)).As(alias), goqu.On(goqu.L("true"))).
+			SelectAppend(goqu.I(alias + "." + alias))
+	}
+}
+
+// embeddedItem returns the dataset selecting the item of ref whose id is
+// value, projected on children, in the column alias.
+func embeddedItem(ref pgsql.EmbeddedReference, children query.Projection, value exp.Expression, alias string) *goqu.SelectDataset {
+	idType := ref.IDType
+	if idType == "" {
+		idType = "TEXT"
+	}
+
+	skipped := make([]string, len(embeddedSkipped))
+	for i, column := range embeddedSkipped {
+		skipped[i] = pq.QuoteLiteral(column)
+	}
+	row := goqu.T("r")
+	item := goqu.Dialect("postgres").From(goqu.T(ref.Table).As("r")).
+		Select(goqu.L("(to_jsonb(?) - ARRAY["+strings.Join(skipped, ", ")+"]) || coalesce(to_jsonb(?)->'payload', '{}')", row, row).As("doc")).
+		Where(
+			goqu.I("r.id").Eq(goqu.Cast(value, idType)),
+			goqu.L("to_jsonb(?)->>? IS NULL", row, pgsql.DeletedColumn),
+		)
+
+	var projected exp.Expression = goqu.I("d.doc")
+	if !hasStar(children) {
+		projected = ProjectedObject(ProjectedFields(children), func(path []string) exp.Expression {
+			args := []any{goqu.I("d.doc")}
+			for _, name := range path {
+				args = append(args, name)
+			}
+			return goqu.L("?"+strings.Repeat("->?", len(path)), args...)
+		})
+	}
+
+	return goqu.Dialect("postgres").From(item.As("d")).Select(goqu.L("?", projected).As(alias))
+}
+
+func hasStar(pj query.Projection) bool {
+	for _, pf := range pj {
+		if pf.Name == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeEmbedded replaces the reference values of payload by the items
+// embedded for them, read from the EmbeddedPrefix columns by field name. A
+// reference to a missing item becomes nil.
+func MergeEmbedded(payload map[string]any, embedded map[string]any) error {
+	for name, v := range embedded {
+		if _, ok := payload[name]; !ok {
+			continue
+		}
+		if v == nil {
+			payload[name] = nil
+			continue
+		}
+		var item map[string]any
+		if err := json.Unmarshal([]byte(v.(string)), &item); err != nil {
+			return err
+		}
+		payload[name] = item
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestEmbed(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"name":   {Validator: &schema.String{}},
+			"author": {Validator: &schema.Reference{Path: "users"}},
+			"editor": {Validator: &schema.Reference{Path: "users"}},
+			"tag":    {Validator: &schema.Reference{Path: "tags"}},
+		},
+	}
+	refs := map[string]pgsql.EmbeddedReference{
+		"users": {Path: "users", Table: "users", IDType: "INTEGER"},
+	}
+	reference := func(name string) exp.Expression {
+		return goqu.L("?->>?", goqu.I("table.payload"), name)
+	}
+
+	builder := goqu.Dialect("postgres").From("table").Select(goqu.Star())
+	Embed(builder, "table", sc, query.Projection{
+		{Name: "name"},
+		{Name: "author", Alias: "by", Children: query.Projection{{Name: "name"}}},
+		{Name: "author", Children: query.Projection{{Name: "id"}}},
+		{Name: "editor", Children: query.Projection{{Name: "*"}}},
+		{Name: "tag", Children: query.Projection{{Name: "label"}}},
+	}, refs, reference)
+
+	sql, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	item := `SELECT (to_jsonb("r") - ARRAY['payload', 'etag', 'updated', '_etag', '_updated', 'deleted_at', 'search_vector']) || coalesce(to_jsonb("r")->'payload', '{}') AS "doc" FROM "users" AS "r"`
+	wantSQL := `SELECT "table".*, "_embedded_author"."_embedded_author", "_embedded_editor"."_embedded_editor" FROM "table" ` +
+		`LEFT JOIN LATERAL (SELECT jsonb_build_object('name', "d"."doc"->$1) AS "_embedded_author" FROM (` + item +
+		` WHERE (("r"."id" = CAST("table"."payload"->>$2 AS INTEGER)) AND to_jsonb("r")->>$3 IS NULL)) AS "d") AS "_embedded_author" ON true ` +
+		`LEFT JOIN LATERAL (SELECT "d"."doc" AS "_embedded_editor" FROM (` + item +
+		` WHERE (("r"."id" = CAST("table"."payload"->>$4 AS INTEGER)) AND to_jsonb("r")->>$5 IS NULL)) AS "d") AS "_embedded_editor" ON true`
+	if sql != wantSQL {
+		t.Errorf("SQL = %s, want %s", sql, wantSQL)
+	}
+	wantArgs := []any{"name", "author", "deleted_at", "editor", "deleted_at"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+}
+
+func TestMergeEmbedded(t *testing.T) {
+	payload := map[string]any{"author": "1", "editor": "2"}
+	err := MergeEmbedded(payload, map[string]any{
+		"author": `{"id": 1, "name": "John"}`,
+		"editor": nil,
+		"owner":  nil,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"author": map[string]any{"id": float64(1), "name": "John"},
+		"editor": nil,
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("Payload = %#v, want %#v", payload, want)
+	}
+}
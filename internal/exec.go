@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/lib/pq"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx used by the stores.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Executor decides where the statements of a store operation run: in the
+// transaction bound to the context, in a transaction of its own when session
+// settings must be applied, or directly on the database.
+type Executor struct {
+	// Table is the table of the store, reported to the query hooks.
+	Table   string
+	Resolve pgsql.DBResolver
+	Options *pgsql.Options
+	// Migrate, when set, is run once on every database returned by Resolve
+	// before its first use.
+	Migrate func(ctx context.Context, db *sql.DB) error
+
+	// locks holds a *sync.Mutex per database, so the first use of one
+	// database does not wait for the migration of another.
+	locks    sync.Map
+	migrated sync.Map
+}
+
+// NewExecutor returns an Executor running every statement of the store of
+// table on db.
+func NewExecutor(table string, db *sql.DB, opts *pgsql.Options) *Executor {
+	return &Executor{
+		Table:   table,
+		Resolve: func(context.Context) (*sql.DB, error) { return db, nil },
+		Options: opts,
+	}
+}
+
+// DB returns the database of the request, migrating it first if needed.
+func (e *Executor) DB(ctx context.Context) (*sql.DB, error) {
+	db, err := e.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if e.Migrate == nil {
+		return db, nil
+	}
+	if _, ok := e.migrated.Load(db); ok {
+		return db, nil
+	}
+
+	lock, _ := e.locks.LoadOrStore(db, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := e.migrated.Load(db); ok {
+		return db, nil
+	}
+	if err := e.Migrate(ctx, db); err != nil {
+		return nil, err
+	}
+	e.migrated.Store(db, struct{}{})
+
+	return db, nil
+}
+
+// Run calls fn with the Querier the statements of operation must use.
+func (e *Executor) Run(ctx context.Context, operation string, fn func(ctx context.Context, q Querier) error) error {
+	if tx := pgsql.TransactionFromContext(ctx); tx != nil || e.Options.SessionSettings != nil {
+		return e.RunTx(ctx, operation, fn)
+	}
+	db, err := e.DB(ctx)
+	if err != nil {
+		return err
+	}
+	q := e.trace(db, operation)
+	err = fn(ctx, q)
+	q.reportSlow(ctx, err == nil)
+	return err
+}
+
+// RunWrite runs the statements of a mutation. Mutations writing side rows,
+// such as history records or outbox events, or publishing notifications
+// always run inside a transaction.
+func (e *Executor) RunWrite(ctx context.Context, operation string, fn func(ctx context.Context, q Querier) error) error {
+	if e.Options.History || e.Options.Publishes() || e.Options.OutboxTable != "" {
+		return e.RunTx(ctx, operation, fn)
+	}
+	return e.Run(ctx, operation, fn)
+}
+
+// RunTx is like Run but always runs fn inside a transaction. If the context
+// does not carry one, a new transaction is started and committed when fn
+// succeeds. The context passed to fn carries the transaction.
+func (e *Executor) RunTx(ctx context.Context, operation string, fn func(ctx context.Context, q Querier) error) (err error) {
+	if tx := pgsql.TransactionFromContext(ctx); tx != nil {
+		// The transaction belongs to the request database, which must be
+		// migrated before use all the same.
+		if e.Migrate != nil {
+			if _, err = e.DB(ctx); err != nil {
+				return err
+			}
+		}
+		q := e.trace(tx, operation)
+		if err = e.applySession(ctx, q); err != nil {
+			return err
+		}
+		err = fn(ctx, q)
+		q.reportSlow(ctx, err == nil)
+		return err
+	}
+
+	db, err := e.DB(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	q := e.trace(tx, operation)
+	if err = e.applySession(ctx, q); err != nil {
+		return err
+	}
+	ctx = pgsql.NewTransactionContext(ctx, tx)
+	err = fn(ctx, q)
+	// The slow reads are explained in the transaction, before it ends.
+	q.reportSlow(ctx, err == nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *Executor) applySession(ctx context.Context, tx Querier) error {
+	if e.Options.SessionSettings == nil {
+		return nil
+	}
+	settings, err := e.Options.SessionSettings(ctx)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range SessionStatements(settings) {
+		if _, err := tx.ExecContext(ctx, stmt.SQL, stmt.Args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Statement is a SQL string with its arguments.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+// SessionStatements returns the statements applying settings to the current
+// transaction. Values are set with set_config(..., true), the parameterized
+// equivalent of SET LOCAL.
+func SessionStatements(settings pgsql.SessionSettings) []Statement {
+	var stmts []Statement
+	if settings.Role != "" {
+		stmts = append(stmts, Statement{SQL: "SET LOCAL ROLE " + pq.QuoteIdentifier(settings.Role)})
+	}
+
+	keys := make([]string, 0, len(settings.Values))
+	for key := range settings.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		stmts = append(stmts, Statement{
+			SQL:  "SELECT set_config($1, $2, true)",
+			Args: []any{pgsql.SettingsPrefix + "." + key, settings.Values[key]},
+		})
+	}
+	return stmts
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+type tenantKey struct{}
+
+func TestExecutor_DB(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": {}, "b": {}}
+	migrations := map[*sql.DB]int{}
+	failing := true
+
+	e := &Executor{
+		Resolve: func(ctx context.Context) (*sql.DB, error) {
+			db, ok := dbs[ctx.Value(tenantKey{}).(string)]
+			if !ok {
+				return nil, errors.New("unknown tenant")
+			}
+			return db, nil
+		},
+		Options: pgsql.NewOptions(),
+		Migrate: func(ctx context.Context, db *sql.DB) error {
+			if db == dbs["b"] && failing {
+				failing = false
+				return errors.New("migration failed")
+			}
+			migrations[db]++
+			return nil
+		},
+	}
+
+	ctxA := context.WithValue(context.Background(), tenantKey{}, "a")
+	ctxB := context.WithValue(context.Background(), tenantKey{}, "b")
+	ctxC := context.WithValue(context.Background(), tenantKey{}, "c")
+
+	for i := 0; i < 3; i++ {
+		db, err := e.DB(ctxA)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if db != dbs["a"] {
+			t.Errorf("DB = %p, want %p", db, dbs["a"])
+		}
+	}
+
+	if _, err := e.DB(ctxB); err == nil {
+		t.Errorf("Expected migration error")
+	}
+	if _, err := e.DB(ctxB); err != nil {
+		t.Errorf("Unexpected error after migration retry: %v", err)
+	}
+	if _, err := e.DB(ctxC); err == nil {
+		t.Errorf("Expected resolver error")
+	}
+
+	if migrations[dbs["a"]] != 1 || migrations[dbs["b"]] != 1 {
+		t.Errorf("Migrations = %v, want one per database", migrations)
+	}
+}
+
+func TestExecutor_DB_perDatabase(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": {}, "b": {}}
+	started, release := make(chan struct{}), make(chan struct{})
+
+	e := &Executor{
+		Resolve: func(ctx context.Context) (*sql.DB, error) {
+			return dbs[ctx.Value(tenantKey{}).(string)], nil
+		},
+		Options: pgsql.NewOptions(),
+		Migrate: func(ctx context.Context, db *sql.DB) error {
+			if db == dbs["b"] {
+				close(started)
+				<-release
+			}
+			return nil
+		},
+	}
+
+	slow := make(chan error)
+	go func() {
+		_, err := e.DB(context.WithValue(context.Background(), tenantKey{}, "b"))
+		slow <- err
+	}()
+	<-started
+
+	done := make(chan error)
+	go func() {
+		_, err := e.DB(context.WithValue(context.Background(), tenantKey{}, "a"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("The migration of a database waited for the one of another")
+	}
+
+	close(release)
+	if err := <-slow; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
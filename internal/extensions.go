@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// ExtensionQueries returns the statements creating the extensions of the
+// PostgresExtender validators of s, nested ones included.
+func ExtensionQueries(s *schema.Schema) []string {
+	names := map[string]bool{}
+	extensions(s, names)
+
+	queries := make([]string, 0, len(names))
+	for name := range names {
+		queries = append(queries, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", pq.QuoteIdentifier(name)))
+	}
+	sort.Strings(queries)
+	return queries
+}
+
+func extensions(s *schema.Schema, names map[string]bool) {
+	if s == nil {
+		return
+	}
+	for _, field := range s.Fields {
+		validatorExtensions(field.Validator, names)
+	}
+}
+
+func validatorExtensions(validator schema.FieldValidator, names map[string]bool) {
+	switch v := validator.(type) {
+	case pgsql.PostgresExtender:
+		names[v.PostgresExtension()] = true
+	case *schema.Object:
+		extensions(v.Schema, names)
+	case *schema.Array:
+		validatorExtensions(v.Values.Validator, names)
+	case *schema.Dict:
+		validatorExtensions(v.Values.Validator, names)
+	}
+}
+
+// Binary reports whether field is stored as BYTEA, whose values are scanned
+// as []byte rather than text.
+func Binary(field schema.Field) bool {
+	t, ok := field.Validator.(pgsql.PostgresTyper)
+	return ok && t.PostgresType() == "BYTEA"
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+type citext struct {
+	schema.String
+}
+
+func (citext) PostgresType() string      { return "CITEXT" }
+func (citext) PostgresExtension() string { return "citext" }
+
+func TestExtensionQueries(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"email": {Validator: &citext{}},
+			"name":  {Validator: &schema.String{}},
+			"contact": {Validator: &schema.Object{Schema: &schema.Schema{
+				Fields: schema.Fields{
+					"aliases": {Validator: &schema.Array{Values: schema.Field{Validator: &citext{}}}},
+				},
+			}}},
+		},
+	}
+
+	want := []string{`CREATE EXTENSION IF NOT EXISTS "citext"`}
+	if got := ExtensionQueries(sc); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtensionQueries() = %v, want %v", got, want)
+	}
+	if got := ExtensionQueries(&schema.Schema{Fields: schema.Fields{"name": {Validator: &schema.String{}}}}); len(got) != 0 {
+		t.Errorf("ExtensionQueries() = %v, want none", got)
+	}
+}
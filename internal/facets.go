@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// PrepareFacets makes builder, already filtering the items of table, count
+// the items by value of each of fields, in grouping sets. typed returns the
+// expression of a field cast to the type of its values, and json its JSONB
+// value. opts are the options of the store. Each field is joined laterally
+// as facet_<i>, to be grouped by a column, and its values are selected as
+// value_<i>.
+func PrepareFacets(builder *goqu.SelectDataset, table string, sc *schema.Schema, opts *pgsql.Options, fields []string, typed, json func(field string) exp.Expression) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("facets: no field")
+	}
+
+	var selects, sets []any
+	for i, name := range fields {
+		field := sc.GetField(name)
+		if field == nil || field.Hidden {
+			return fmt.Errorf("facets: unknown field %s", name)
+		}
+		if !field.Filterable {
+			return fmt.Errorf("facets: field %s is not filterable", name)
+		}
+
+		alias := fmt.Sprintf("facet_%d", i)
+		column := goqu.I(alias + ".value")
+		var values exp.Expression
+		var value any = column
+		switch v := field.Validator.(type) {
+		case *schema.Array:
+			values = goqu.L("jsonb_array_elements_text(CASE WHEN jsonb_typeof(?) = 'array' THEN ? END)", json(name), json(name))
+			if pgType := castType(v.Values.Validator, opts); pgType != "" {
+				value = goqu.Cast(column, pgType)
+			}
+		case *schema.Dict:
+			values = goqu.L("jsonb_object_keys(CASE WHEN jsonb_typeof(?) = 'object' THEN ? END)", json(name), json(name))
+		default:
+			values = goqu.L("(SELECT ?)", typed(name))
+		}
+
+		*builder = *builder.LeftJoin(goqu.L("LATERAL ? AS ?(?)", values, goqu.I(alias), goqu.I("value")), goqu.On(goqu.L("true")))
+		selects = append(selects,
+			goqu.L("?", value).As(fmt.Sprintf("value_%d", i)),
+			goqu.L("GROUPING(?)", column).As(fmt.Sprintf("grouping_%d", i)),
+		)
+		sets = append(sets, column)
+	}
+	selects = append(selects, goqu.L("COUNT(DISTINCT ?)", goqu.I(table+".id")).As("count"))
+
+	*builder = *builder.
+		Select(selects...).
+		GroupBy(goqu.L("GROUPING SETS ("+strings.TrimSuffix(strings.Repeat("(?), ", len(sets)), ", ")+")", sets...)).
+		Order(goqu.C("count").Desc())
+	return nil
+}
+
+// ScanFacets reads the rows selected by PrepareFacets.
+func ScanFacets(rows *sql.Rows, sc *schema.Schema, fields []string) (map[string][]pgsql.FacetValue, error) {
+	facets := make(map[string][]pgsql.FacetValue, len(fields))
+	for _, name := range fields {
+		facets[name] = []pgsql.FacetValue{}
+	}
+
+	for rows.Next() {
+		vals := make([]any, 2*len(fields))
+		ptrs := make([]any, len(vals)+1)
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		var count int64
+		ptrs[len(vals)] = &count
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		for i, name := range fields {
+			if grouping, _ := vals[2*i+1].(int64); grouping != 0 || vals[2*i] == nil {
+				continue
+			}
+			field := sc.GetField(name)
+			if array, ok := field.Validator.(*schema.Array); ok {
+				field = &array.Values
+			}
+			facets[name] = append(facets[name], pgsql.FacetValue{
+				Value: aggregateValue(field, vals[2*i]),
+				Count: count,
+			})
+		}
+	}
+	return facets, rows.Err()
+}
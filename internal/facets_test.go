@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestPrepareFacets(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"status": {Validator: &schema.String{}, Filterable: true},
+			"tags": {Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.String{}},
+			}, Filterable: true},
+			"sizes": {Validator: &schema.Array{
+				Values: schema.Field{Validator: &schema.Integer{}},
+			}, Filterable: true},
+			"labels": {Validator: &schema.Dict{}, Filterable: true},
+			"note":   {Validator: &schema.String{}},
+			"secret": {Validator: &schema.String{}, Filterable: true, Hidden: true},
+		},
+	}
+	typed := func(field string) exp.Expression {
+		return goqu.L("payload->>?", field)
+	}
+	json := func(field string) exp.Expression {
+		return goqu.L("payload->?", field)
+	}
+
+	builder := goqu.Dialect("postgres").From("table").Where(goqu.C("owner").Eq("alice"))
+	if err := PrepareFacets(builder, "table", sc, &pgsql.Options{}, []string{"status", "tags", "sizes", "labels"}, typed, json); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sql, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantSQL := `SELECT "facet_0"."value" AS "value_0", GROUPING("facet_0"."value") AS "grouping_0", ` +
+		`"facet_1"."value" AS "value_1", GROUPING("facet_1"."value") AS "grouping_1", ` +
+		`CAST("facet_2"."value" AS INTEGER) AS "value_2", GROUPING("facet_2"."value") AS "grouping_2", ` +
+		`"facet_3"."value" AS "value_3", GROUPING("facet_3"."value") AS "grouping_3", ` +
+		`COUNT(DISTINCT "table"."id") AS "count" FROM "table" ` +
+		`LEFT JOIN LATERAL (SELECT payload->>$1) AS "facet_0"("value") ON true ` +
+		`LEFT JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof(payload->$2) = 'array' THEN payload->$3 END) AS "facet_1"("value") ON true ` +
+		`LEFT JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof(payload->$4) = 'array' THEN payload->$5 END) AS "facet_2"("value") ON true ` +
+		`LEFT JOIN LATERAL jsonb_object_keys(CASE WHEN jsonb_typeof(payload->$6) = 'object' THEN payload->$7 END) AS "facet_3"("value") ON true ` +
+		`WHERE ("owner" = $8) ` +
+		`GROUP BY GROUPING SETS (("facet_0"."value"), ("facet_1"."value"), ("facet_2"."value"), ("facet_3"."value")) ` +
+		`ORDER BY "count" DESC`
+	if sql != wantSQL {
+		t.Errorf("SQL = %s, want %s", sql, wantSQL)
+	}
+	wantArgs := []any{"status", "tags", "tags", "sizes", "sizes", "labels", "labels", "alice"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+
+	for field, want := range map[string]string{
+		"missing": "facets: unknown field missing",
+		"secret":  "facets: unknown field secret",
+		"note":    "facets: field note is not filterable",
+	} {
+		if err := PrepareFacets(goqu.From("table"), "table", sc, &pgsql.Options{}, []string{field}, typed, json); err == nil || err.Error() != want {
+			t.Errorf("Error = %v, want %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// PointType is the type of the columns of point fields.
+const PointType = "POINT"
+
+// PointField tells if the field at path of s is a point field.
+func PointField(s *schema.Schema, path string) bool {
+	if s == nil {
+		return false
+	}
+	f := s.GetField(path)
+	if f == nil {
+		return false
+	}
+	t, ok := f.Validator.(pgsql.PostgresTyper)
+	return ok && t.PostgresType() == PointType
+}
+
+// PointPaths returns the paths of the point fields of s, nested ones
+// included, sorted.
+func PointPaths(s *schema.Schema) []string {
+	if s == nil {
+		return nil
+	}
+
+	var paths []string
+	for name, field := range s.Fields {
+		switch v := field.Validator.(type) {
+		case pgsql.PostgresTyper:
+			if v.PostgresType() == PointType {
+				paths = append(paths, name)
+			}
+		case *schema.Object:
+			for _, nested := range PointPaths(v.Schema) {
+				paths = append(paths, name+"."+nested)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// JSONPoint returns the point of the lat/lng object at path in the JSONB
+// column, the longitude being x. Predicates use the expression indexed by
+// PointIndexQueries.
+func JSONPoint(column, path string) string {
+	return fmt.Sprintf("point(%s::float8, %s::float8)", JSONText(column, path+".lng"), JSONText(column, path+".lat"))
+}
+
+// PointIndexQueries returns the statements creating the GiST indexes of the
+// point fields of s in table. point returns the SQL expression of the point
+// at a dotted path: a point column or a JSONPoint.
+func PointIndexQueries(table string, s *schema.Schema, point func(path string) string) []string {
+	paths := PointPaths(s)
+	queries := make([]string, 0, len(paths))
+	for _, path := range paths {
+		queries = append(queries, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIST (%s)",
+			pq.QuoteIdentifier(table+"_"+strings.ReplaceAll(path, ".", "_")+"_idx"), pq.QuoteIdentifier(table), point(path)))
+	}
+	return queries
+}
+
+// GeoPredicate returns the expression of e when it is a WithinBox or a Near
+// predicate. point returns the point expression of a field. ok is false for
+// other expressions.
+func GeoPredicate(e query.Expression, point func(field string) exp.Expression) (expr exp.Expression, ok bool) {
+	switch t := e.(type) {
+	case *pgsql.WithinBox:
+		return withinBox(point(t.Field), t.South, t.West, t.North, t.East), true
+	case *pgsql.Near:
+		column := point(t.Field)
+		south, west, north, east := nearBox(t)
+		// The box is matched first, with the index
+		return goqu.And(
+			withinBox(column, south, west, north, east),
+			goqu.L("? <= ?", distance(column, t.Lat, t.Lng), t.Radius),
+		), true
+	}
+	return nil, false
+}
+
+// GeoDistance returns the distance, in meters, of the point of field to the
+// center of the Near predicate on field found in p, the order of a sort on
+// field. Without such a predicate, there is no order to sort by.
+func GeoDistance(field string, p query.Predicate, point exp.Expression) (exp.LiteralExpression, error) {
+	near, ok := nearTerm(field, p)
+	if !ok {
+		return nil, fmt.Errorf("sort: %s: sorting on a point field needs a $near predicate on it", field)
+	}
+	return distance(point, near.Lat, near.Lng), nil
+}
+
+func withinBox(point exp.Expression, south, west, north, east float64) exp.Expression {
+	if west <= east {
+		return goqu.L("? <@ box(point(?, ?), point(?, ?))", point, west, south, east, north)
+	}
+	return goqu.Or(
+		goqu.L("? <@ box(point(?, ?), point(?, ?))", point, west, south, 180.0, north),
+		goqu.L("? <@ box(point(?, ?), point(?, ?))", point, -180.0, south, east, north),
+	)
+}
+
+// nearBox returns the bounds of the box around the circle of near.
+func nearBox(near *pgsql.Near) (south, west, north, east float64) {
+	angle := near.Radius / pgsql.EarthRadius
+	delta := angle * 180 / math.Pi
+	south, north = near.Lat-delta, near.Lat+delta
+	if south <= -90 || north >= 90 || angle >= math.Pi/2 {
+		// The circle includes a pole
+		return math.Max(south, -90), -180, math.Min(north, 90), 180
+	}
+
+	ratio := math.Sin(angle) / math.Cos(near.Lat*math.Pi/180)
+	if ratio >= 1 {
+		return south, -180, north, 180
+	}
+	delta = math.Asin(ratio) * 180 / math.Pi
+	west, east = near.Lng-delta, near.Lng+delta
+	if west < -180 {
+		west += 360
+	}
+	if east > 180 {
+		east -= 360
+	}
+	return south, west, north, east
+}
+
+// distance returns the great-circle distance, in meters, of point to the
+// point at lat and lng.
+func distance(point exp.Expression, lat, lng float64) exp.LiteralExpression {
+	return goqu.L(
+		"(2 * ? * asin(least(1, sqrt(power(sin(radians((?)[1] - ?) / 2), 2) + cos(radians(?)) * cos(radians((?)[1])) * power(sin(radians((?)[0] - ?) / 2), 2)))))",
+		pgsql.EarthRadius, point, lat, lat, point, point, lng,
+	)
+}
+
+// nearTerm returns the Near predicate on field, at the top level of p or of
+// its conjunctions.
+func nearTerm(field string, p query.Predicate) (*pgsql.Near, bool) {
+	for _, e := range p {
+		switch t := e.(type) {
+		case *pgsql.Near:
+			if t.Field == field {
+				return t, true
+			}
+		case *query.And:
+			if near, ok := nearTerm(field, query.Predicate(*t)); ok {
+				return near, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+type point struct{ schema.String }
+
+func (point) PostgresType() string { return PointType }
+
+func TestGeoPredicate(t *testing.T) {
+	column := func(field string) exp.Expression {
+		return goqu.C(field)
+	}
+
+	tests := []struct {
+		name     string
+		e        query.Expression
+		wantOK   bool
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:   "not a geo predicate",
+			e:      &query.Equal{Field: "home", Value: "x"},
+			wantOK: false,
+		},
+		{
+			name:     "WithinBox",
+			e:        &pgsql.WithinBox{Field: "home", South: 48, West: 2, North: 49, East: 3},
+			wantOK:   true,
+			wantSQL:  `SELECT * FROM "table" WHERE "home" <@ box(point($1, $2), point($3, $4))`,
+			wantArgs: []any{2.0, 48.0, 3.0, 49.0},
+		},
+		{
+			name:     "WithinBox: antimeridian",
+			e:        &pgsql.WithinBox{Field: "home", South: -20, West: 170, North: -10, East: -170},
+			wantOK:   true,
+			wantSQL:  `SELECT * FROM "table" WHERE ("home" <@ box(point($1, $2), point($3, $4)) OR "home" <@ box(point($5, $6), point($7, $8)))`,
+			wantArgs: []any{170.0, -20.0, 180.0, -10.0, -180.0, -20.0, -170.0, -10.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, ok := GeoPredicate(tt.e, column)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			sql, args, err := goqu.Dialect("postgres").From("table").Where(expr).Prepared(true).ToSQL()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGeoPredicate_near(t *testing.T) {
+	near := &pgsql.Near{Field: "home", Lat: 48.8566, Lng: 2.3522, Radius: 10_000}
+	expr, ok := GeoPredicate(near, func(field string) exp.Expression {
+		return goqu.C(field)
+	})
+	if !ok {
+		t.Fatal("ok = false, want true")
+	}
+	sql, args, err := goqu.Dialect("postgres").From("table").Where(expr).Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `SELECT * FROM "table" WHERE ("home" <@ box(point($1, $2), point($3, $4)) AND (2 * $5 * asin(least(1, sqrt(power(sin(radians(("home")[1] - $6) / 2), 2) + cos(radians($7)) * cos(radians(("home")[1])) * power(sin(radians(("home")[0] - $8) / 2), 2))))) <= $9)`
+	if sql != want {
+		t.Errorf("SQL = %s, want %s", sql, want)
+	}
+	if len(args) != 9 || args[8] != 10_000.0 {
+		t.Errorf("Args = %#v", args)
+	}
+
+	// The box holds the circle
+	west, south, east, north := args[0].(float64), args[1].(float64), args[2].(float64), args[3].(float64)
+	for _, bearing := range []float64{0, 90, 180, 270} {
+		lat, lng := destination(near.Lat, near.Lng, near.Radius, bearing)
+		if lat < south || lat > north || lng < west || lng > east {
+			t.Errorf("Point at %v° (%f, %f) is outside of the box", bearing, lat, lng)
+		}
+	}
+}
+
+// destination returns the point at distance meters of lat and lng, in the
+// direction of bearing degrees.
+func destination(lat, lng, distance, bearing float64) (float64, float64) {
+	rad := math.Pi / 180
+	angle := distance / pgsql.EarthRadius
+	lat2 := math.Asin(math.Sin(lat*rad)*math.Cos(angle) + math.Cos(lat*rad)*math.Sin(angle)*math.Cos(bearing*rad))
+	lng2 := lng*rad + math.Atan2(math.Sin(bearing*rad)*math.Sin(angle)*math.Cos(lat*rad), math.Cos(angle)-math.Sin(lat*rad)*math.Sin(lat2))
+	return lat2 / rad, lng2 / rad
+}
+
+func TestNearBox(t *testing.T) {
+	south, west, north, east := nearBox(&pgsql.Near{Lat: 89.9, Lng: 0, Radius: 50_000})
+	if west != -180 || east != 180 || north != 90 || south > 89.9 {
+		t.Errorf("nearBox() = %f, %f, %f, %f, want a box around the pole", south, west, north, east)
+	}
+	_, west, _, east = nearBox(&pgsql.Near{Lat: 0, Lng: 179.99, Radius: 10_000})
+	if west <= east {
+		t.Errorf("nearBox() = %f..%f, want a box crossing the antimeridian", west, east)
+	}
+}
+
+func TestGeoDistance(t *testing.T) {
+	p := query.Predicate{
+		&query.Equal{Field: "name", Value: "john"},
+		&query.And{&pgsql.Near{Field: "home", Lat: 1, Lng: 2, Radius: 3}},
+	}
+	if _, err := GeoDistance("home", p, goqu.C("home")); err != nil {
+		t.Errorf("GeoDistance() = %v, want the Near predicate", err)
+	}
+	if _, err := GeoDistance("office", p, goqu.C("office")); err == nil {
+		t.Error("GeoDistance() found a Near predicate on another field")
+	}
+}
+
+func TestPointIndexQueries(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"home": {Validator: point{}},
+			"name": {Validator: &schema.String{}},
+			"office": {Validator: &schema.Object{Schema: &schema.Schema{
+				Fields: schema.Fields{"location": {Validator: point{}}},
+			}}},
+		},
+	}
+	got := PointIndexQueries("table", sc, func(path string) string {
+		return JSONPoint("payload", path)
+	})
+	want := []string{
+		`CREATE INDEX IF NOT EXISTS "table_home_idx" ON "table" USING GIST (point(("payload" #>> '{home,lng}')::float8, ("payload" #>> '{home,lat}')::float8))`,
+		`CREATE INDEX IF NOT EXISTS "table_office_location_idx" ON "table" USING GIST (point(("payload" #>> '{office,location,lng}')::float8, ("payload" #>> '{office,location,lat}')::float8))`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PointIndexQueries() = %#v, want %#v", got, want)
+	}
+}
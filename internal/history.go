@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+var historyColumns = []any{
+	"revision", "item_id", "operation", "actor", "changed_at",
+	"old_etag", "new_etag", "old_updated", "new_updated", "old_payload", "new_payload",
+}
+
+// HistoryQueries returns the statements creating the history table of table.
+func HistoryQueries(table string, opts *pgsql.Options) []string {
+	if !opts.History {
+		return nil
+	}
+	history := table + pgsql.HistorySuffix
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (`+
+			`revision BIGSERIAL PRIMARY KEY,`+
+			`item_id VARCHAR NOT NULL,`+
+			`operation VARCHAR(6) NOT NULL,`+
+			`actor VARCHAR,`+
+			`changed_at %[2]s NOT NULL DEFAULT now(),`+
+			`old_etag VARCHAR(32),new_etag VARCHAR(32),`+
+			`old_updated %[2]s,new_updated %[2]s,`+
+			`old_payload JSONB,new_payload JSONB)`, pq.QuoteIdentifier(history), TimeType(opts)),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (item_id, revision)`,
+			pq.QuoteIdentifier(history+"_item_idx"), pq.QuoteIdentifier(history)),
+	}
+	return append(queries, TimeZoneQueries(history, opts, "changed_at", "old_updated", "new_updated")...)
+}
+
+// PrepareRevision returns the statement recording a change of an item in the
+// history table of table. old is nil for inserts, new is nil for deletes.
+func PrepareRevision(ctx context.Context, table, operation string, old, new *resource.Item) (string, []any, error) {
+	item := new
+	if item == nil {
+		item = old
+	}
+
+	row := goqu.Record{
+		"item_id":   fmt.Sprint(item.ID),
+		"operation": operation,
+		"actor":     nullString(pgsql.ActorFromContext(ctx)),
+	}
+	if old != nil {
+		payload, err := encodePayload(old.Payload)
+		if err != nil {
+			return "", nil, err
+		}
+		row["old_etag"] = old.ETag
+		row["old_updated"] = old.Updated
+		row["old_payload"] = payload
+	}
+	if new != nil {
+		payload, err := encodePayload(new.Payload)
+		if err != nil {
+			return "", nil, err
+		}
+		row["new_etag"] = new.ETag
+		row["new_updated"] = new.Updated
+		row["new_payload"] = payload
+	}
+
+	return goqu.Dialect("postgres").Insert(table + pgsql.HistorySuffix).Rows(row).Prepared(true).ToSQL()
+}
+
+// RecordRevision records a change of an item, see PrepareRevision.
+func RecordRevision(ctx context.Context, q Querier, table, operation string, old, new *resource.Item) error {
+	sqlStr, args, err := PrepareRevision(ctx, table, operation, old, new)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// WithDeleteHistory wraps a DELETE (or soft delete UPDATE) of table in a
+// statement recording every removed row in the history table. The returned
+// rows expose etagColumn and updatedColumn, and payload builds the JSONB
+// payload of a returned row.
+func WithDeleteHistory(ctx context.Context, table string, removal exp.AppendableExpression, etagColumn, updatedColumn string, payload exp.Expression) *goqu.InsertDataset {
+	return goqu.Dialect("postgres").Insert(table+pgsql.HistorySuffix).
+		With("removed", removal).
+		Cols("item_id", "operation", "actor", "old_etag", "old_updated", "old_payload").
+		FromQuery(goqu.From("removed").Select(
+			goqu.Cast(goqu.I("removed.id"), "VARCHAR"),
+			goqu.V(pgsql.OperationDelete),
+			goqu.V(nullString(pgsql.ActorFromContext(ctx))),
+			goqu.I("removed."+etagColumn),
+			goqu.I("removed."+updatedColumn),
+			payload,
+		))
+}
+
+// ListRevisions returns the revisions of the item id, oldest first.
+func ListRevisions(ctx context.Context, q Querier, table string, id any) ([]*pgsql.Revision, error) {
+	sqlStr, args, err := goqu.Dialect("postgres").From(table + pgsql.HistorySuffix).
+		Select(historyColumns...).
+		Where(goqu.C("item_id").Eq(fmt.Sprint(id))).
+		Order(goqu.C("revision").Asc()).
+		Prepared(true).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*pgsql.Revision
+	for rows.Next() {
+		var rev pgsql.Revision
+		var actor, oldETag, newETag sql.NullString
+		var oldUpdated, newUpdated sql.NullTime
+		var oldPayload, newPayload []byte
+		err := rows.Scan(&rev.Revision, &rev.ItemID, &rev.Operation, &actor, &rev.ChangedAt,
+			&oldETag, &newETag, &oldUpdated, &newUpdated, &oldPayload, &newPayload)
+		if err != nil {
+			return nil, err
+		}
+		rev.Actor = actor.String
+		rev.OldETag = oldETag.String
+		rev.NewETag = newETag.String
+		rev.OldUpdated = oldUpdated.Time
+		rev.NewUpdated = newUpdated.Time
+		if rev.OldPayload, err = decodePayload(oldPayload); err != nil {
+			return nil, err
+		}
+		if rev.NewPayload, err = decodePayload(newPayload); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func encodePayload(payload map[string]any) (string, error) {
+	buf := bytes.Buffer{}
+	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func decodePayload(b []byte) (map[string]any, error) {
+	if b == nil {
+		return nil, nil
+	}
+	payload := make(map[string]any)
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// AsOf returns a dataset selecting columns from the last revision of every
+// item of table changed at or before at, skipping deleted items. The
+// revision columns are available unqualified to columns.
+func AsOf(table string, at time.Time, columns ...any) *goqu.SelectDataset {
+	latest := goqu.Dialect("postgres").From(table+pgsql.HistorySuffix).
+		Distinct("item_id").
+		Where(goqu.C("changed_at").Lte(at)).
+		Order(goqu.C("item_id").Asc(), goqu.C("revision").Desc())
+
+	return goqu.Dialect("postgres").From(latest.As("revision")).
+		Select(columns...).
+		Where(goqu.C("operation").Neq(pgsql.OperationDelete))
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestPrepareRevision(t *testing.T) {
+	ctx := pgsql.NewActorContext(context.Background(), "alice")
+	updated := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := &resource.Item{ID: "1", ETag: "a", Updated: updated, Payload: map[string]any{"name": "John"}}
+	new := &resource.Item{ID: "1", ETag: "b", Updated: updated, Payload: map[string]any{"name": "Jane"}}
+
+	sqlStr, args, err := PrepareRevision(ctx, "table", pgsql.OperationUpdate, old, new)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantSQL := `INSERT INTO "table_history" ("actor", "item_id", "new_etag", "new_payload", "new_updated", "old_etag", "old_payload", "old_updated", "operation") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	if sqlStr != wantSQL {
+		t.Errorf("SQL = %s, want %s", sqlStr, wantSQL)
+	}
+	wantArgs := []any{"alice", "1", "b", "{\"name\":\"Jane\"}\n", updated, "a", "{\"name\":\"John\"}\n", updated, "update"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+
+	sqlStr, args, err = PrepareRevision(context.Background(), "table", pgsql.OperationInsert, nil, new)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantSQL = `INSERT INTO "table_history" ("actor", "item_id", "new_etag", "new_payload", "new_updated", "operation") VALUES ($1, $2, $3, $4, $5, $6)`
+	if sqlStr != wantSQL {
+		t.Errorf("SQL = %s, want %s", sqlStr, wantSQL)
+	}
+	if args[0] != nil {
+		t.Errorf("Actor = %#v, want nil", args[0])
+	}
+}
+
+func TestWithDeleteHistory(t *testing.T) {
+	ctx := pgsql.NewActorContext(context.Background(), "alice")
+	removal := goqu.Dialect("postgres").Delete("table").Where(goqu.C("name").Eq("John")).Returning(goqu.Star())
+
+	sqlStr, args, err := WithDeleteHistory(ctx, "table", removal, "etag", "updated", goqu.I("removed.payload")).Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantSQL := `WITH removed AS (DELETE FROM "table" WHERE ("name" = $1) RETURNING *) ` +
+		`INSERT INTO "table_history" ("item_id", "operation", "actor", "old_etag", "old_updated", "old_payload") ` +
+		`SELECT CAST("removed"."id" AS VARCHAR), $2, $3, "removed"."etag", "removed"."updated", "removed"."payload" FROM "removed"`
+	if sqlStr != wantSQL {
+		t.Errorf("SQL = %s, want %s", sqlStr, wantSQL)
+	}
+	wantArgs := []any{"John", "delete", "alice"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// tracedQuerier runs the statements of an operation on a Querier, notifying
+// the query hooks of every statement, logging it and capturing it when slow.
+type tracedQuerier struct {
+	q         Querier
+	operation string
+	table     string
+	hooks     []pgsql.QueryHook
+	slow      *pgsql.SlowQueries
+	slowReads []*pgsql.SlowQuery
+	// inTx tells if q is a transaction.
+	inTx bool
+}
+
+// Trace returns a Querier running the statements of operation on q,
+// notifying the query hooks of the store and logging the statements at the
+// debug level.
+func (e *Executor) Trace(q Querier, operation string) Querier {
+	return e.trace(q, operation)
+}
+
+func (e *Executor) trace(q Querier, operation string) *tracedQuerier {
+	if t, ok := q.(*tracedQuerier); ok {
+		q = t.q
+	}
+	_, inTx := q.(*sql.Tx)
+	return &tracedQuerier{
+		q:         q,
+		operation: operation,
+		table:     e.Table,
+		hooks:     e.Options.QueryHooks,
+		slow:      e.Options.SlowQueries,
+		inTx:      inTx,
+	}
+}
+
+func (t *tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, event, start := t.before(ctx, query, args)
+	result, err := t.q.ExecContext(ctx, query, args...)
+	if err == nil {
+		event.RowsAffected, _ = result.RowsAffected()
+	}
+	t.after(ctx, event, start, err)
+	return result, err
+}
+
+func (t *tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, event, start := t.before(ctx, query, args)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	t.after(ctx, event, start, err)
+	return rows, err
+}
+
+func (t *tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, event, start := t.before(ctx, query, args)
+	row := t.q.QueryRowContext(ctx, query, args...)
+	t.after(ctx, event, start, row.Err())
+	return row
+}
+
+func (t *tracedQuerier) before(ctx context.Context, query string, args []any) (context.Context, *pgsql.QueryEvent, time.Time) {
+	event := &pgsql.QueryEvent{
+		Operation:    t.operation,
+		Table:        t.table,
+		SQL:          query,
+		Args:         args,
+		RowsAffected: -1,
+	}
+	for _, hook := range t.hooks {
+		ctx = hook.BeforeQuery(ctx, event)
+	}
+	return ctx, event, time.Now()
+}
+
+func (t *tracedQuerier) after(ctx context.Context, event *pgsql.QueryEvent, start time.Time, err error) {
+	event.Duration = time.Since(start)
+	event.Err = err
+
+	slog.DebugContext(ctx, "pgsql.Query", "operation", event.Operation, "table", event.Table,
+		"sql", event.SQL, "args", event.Args, "duration", event.Duration, "rows", event.RowsAffected, "error", event.Err)
+	for i := len(t.hooks) - 1; i >= 0; i-- {
+		t.hooks[i].AfterQuery(ctx, event)
+	}
+	t.capture(ctx, event)
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+type hookKey struct{}
+
+// recordingHook records the events it is notified of, tagging the context
+// with its name.
+type recordingHook struct {
+	name  string
+	calls *[]string
+	after []pgsql.QueryEvent
+}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, event *pgsql.QueryEvent) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, hookKey{}, h.name)
+}
+
+func (h *recordingHook) AfterQuery(ctx context.Context, event *pgsql.QueryEvent) {
+	*h.calls = append(*h.calls, "after "+h.name+" in "+ctx.Value(hookKey{}).(string))
+	h.after = append(h.after, *event)
+}
+
+// execQuerier is a Querier whose ExecContext returns result and err.
+type execQuerier struct {
+	Querier
+	result sql.Result
+	err    error
+	ctx    context.Context
+}
+
+func (q *execQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	q.ctx = ctx
+	return q.result, q.err
+}
+
+func TestExecutor_Trace(t *testing.T) {
+	var calls []string
+	first := &recordingHook{name: "first", calls: &calls}
+	second := &recordingHook{name: "second", calls: &calls}
+	e := &Executor{Table: "table", Options: pgsql.NewOptions(pgsql.WithQueryHook(first), pgsql.WithQueryHook(second))}
+
+	q := &execQuerier{result: driverResult(3)}
+	traced := e.Trace(e.Trace(q, "Insert"), "Update")
+	if _, err := traced.ExecContext(context.Background(), "UPDATE x SET y = $1", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantCalls := []string{"before first", "before second", "after second in second", "after first in second"}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("Calls = %v, want %v", calls, wantCalls)
+	}
+	if q.ctx.Value(hookKey{}) != "second" {
+		t.Error("The statement did not run with the context of the hooks")
+	}
+
+	event := first.after[0]
+	if event.Operation != "Update" || event.Table != "table" || event.SQL != "UPDATE x SET y = $1" ||
+		!reflect.DeepEqual(event.Args, []any{1}) || event.RowsAffected != 3 || event.Err != nil || event.Duration < 0 {
+		t.Errorf("Event = %+v", event)
+	}
+
+	q.err = errors.New("failed")
+	q.result = nil
+	if _, err := traced.ExecContext(context.Background(), "DELETE FROM x"); err != q.err {
+		t.Fatalf("Error = %v, want %v", err, q.err)
+	}
+	if event := second.after[1]; event.Err != q.err || event.RowsAffected != -1 {
+		t.Errorf("Event = %+v", event)
+	}
+}
+
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
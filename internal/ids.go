@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// IDs returns the id strategy of a store of s: the one of opts, else the
+// validator of the id field when it is one, else pgsql.XID.
+func IDs(s *schema.Schema, opts *pgsql.Options) pgsql.IDStrategy {
+	if ids, ok := ConfiguredIDs(s, opts); ok {
+		return ids
+	}
+	return pgsql.XID{}
+}
+
+// ConfiguredIDs returns the id strategy of opts, else the validator of the
+// id field of s when it is one. It tells whether there was any.
+func ConfiguredIDs(s *schema.Schema, opts *pgsql.Options) (pgsql.IDStrategy, bool) {
+	if opts != nil && opts.IDStrategy != nil {
+		return opts.IDStrategy, true
+	}
+	if s != nil {
+		if ids, ok := s.Fields["id"].Validator.(pgsql.IDStrategy); ok {
+			return ids, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,17 @@
+package internal
+
+import (
+	"time"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// Measure records the operation of the store of table, started at start and
+// returning *err, in the metrics of opts if any. Operations defer it with
+// their named error result.
+func Measure(opts *pgsql.Options, table, operation string, start time.Time, err *error) {
+	if opts.Metrics == nil {
+		return
+	}
+	opts.Metrics.ObserveOperation(table, operation, pgsql.Outcome(*err), time.Since(start))
+}
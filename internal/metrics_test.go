@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+type operation struct {
+	table, operation, outcome string
+}
+
+// recordingMetrics records the operations it observes.
+type recordingMetrics []operation
+
+func (m *recordingMetrics) ObserveOperation(table, op, outcome string, duration time.Duration) {
+	*m = append(*m, operation{table, op, outcome})
+}
+
+func TestMeasure(t *testing.T) {
+	Measure(&pgsql.Options{}, "users", "Find", time.Now(), new(error))
+
+	var metrics recordingMetrics
+	opts := &pgsql.Options{Metrics: &metrics}
+	find := func() (err error) {
+		defer Measure(opts, "users", "Find", time.Now(), &err)
+		return nil
+	}
+	update := func() (err error) {
+		defer Measure(opts, "users", "Update", time.Now(), &err)
+		return pgsql.NewPreconditionFailed("abc")
+	}
+	clear := func() (err error) {
+		defer Measure(opts, "users", "Clear", time.Now(), &err)
+		return errors.New("failed")
+	}
+	find()
+	update()
+	clear()
+
+	want := []operation{
+		{"users", "Find", pgsql.OutcomeOK},
+		{"users", "Update", pgsql.OutcomePreconditionFailed},
+		{"users", "Clear", pgsql.OutcomeError},
+	}
+	if len(metrics) != len(want) {
+		t.Fatalf("observed %v, want %v", metrics, want)
+	}
+	for i := range want {
+		if metrics[i] != want[i] {
+			t.Errorf("operation %d = %v, want %v", i, metrics[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// Publish sends changes on the notification channel of the store, unless the
+// store does not publish notifications itself.
+func Publish(ctx context.Context, q Querier, opts *pgsql.Options, changes ...pgsql.Change) error {
+	if !opts.Publishes() || len(changes) == 0 {
+		return nil
+	}
+
+	payloads := make([]string, len(changes))
+	for i, change := range changes {
+		b, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		payloads[i] = string(b)
+	}
+
+	_, err := q.ExecContext(ctx, "SELECT pg_notify($1, payload) FROM unnest($2::text[]) AS payload", opts.NotifyChannel, pq.Array(payloads))
+	return err
+}
+
+// NotifyTriggerQueries returns the statements creating the trigger publishing
+// the changes of table, when notifications are published by triggers.
+func NotifyTriggerQueries(table, etagColumn string, opts *pgsql.Options) []string {
+	if opts.NotifyChannel == "" || !opts.NotifyTriggers {
+		return nil
+	}
+
+	name := pq.QuoteIdentifier(table + "_notify")
+	channel := pq.QuoteLiteral(opts.NotifyChannel)
+	change := func(row, operation string) string {
+		return fmt.Sprintf("PERFORM pg_notify(%s, json_build_object('table', TG_TABLE_NAME, 'id', %s.id::text, 'etag', %s.%s, 'operation', %s)::text);",
+			channel, row, row, pq.QuoteIdentifier(etagColumn), operation)
+	}
+
+	body := []string{
+		"BEGIN",
+		"IF TG_OP = 'DELETE' THEN",
+		change("OLD", "'delete'"),
+		"RETURN OLD;",
+		"END IF;",
+	}
+	if opts.SoftDelete {
+		// Soft deletes and restores are updates of the deletion column
+		deleted := pq.QuoteIdentifier(pgsql.DeletedColumn)
+		body = append(body,
+			fmt.Sprintf("IF TG_OP = 'UPDATE' AND OLD.%s IS NULL AND NEW.%s IS NOT NULL THEN", deleted, deleted),
+			change("NEW", "'delete'"),
+			"RETURN NEW;",
+			fmt.Sprintf("ELSIF TG_OP = 'UPDATE' AND OLD.%s IS NOT NULL AND NEW.%s IS NULL THEN", deleted, deleted),
+			change("NEW", "'insert'"),
+			"RETURN NEW;",
+			"END IF;",
+		)
+	}
+	body = append(body,
+		change("NEW", "lower(TG_OP)"),
+		"RETURN NEW;",
+		"END",
+	)
+
+	return []string{
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$ %s $$ LANGUAGE plpgsql", name, strings.Join(body, " ")),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", name, pq.QuoteIdentifier(table)),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", name, pq.QuoteIdentifier(table), name),
+	}
+}
+
+// RecordChange runs the side effects of a change of an item made by a store
+// in the transaction of the change: recording its revision, writing its
+// outbox event and publishing its notification. old is nil for inserts, new
+// is nil for deletes.
+func RecordChange(ctx context.Context, q Querier, table string, opts *pgsql.Options, operation string, old, new *resource.Item) error {
+	if opts.History {
+		if err := RecordRevision(ctx, q, table, operation, old, new); err != nil {
+			return err
+		}
+	}
+
+	item := new
+	if item == nil {
+		item = old
+	}
+
+	if opts.OutboxTable != "" {
+		sqlStr, args, err := PrepareEvent(ctx, table, opts, operation, item)
+		if err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, sqlStr, args...); err != nil {
+			return err
+		}
+	}
+
+	return Publish(ctx, q, opts, pgsql.Change{
+		Table:     table,
+		ID:        fmt.Sprint(item.ID),
+		ETag:      item.ETag,
+		Operation: operation,
+	})
+}
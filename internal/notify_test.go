@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestNotifyTriggerQueries(t *testing.T) {
+	if got := NotifyTriggerQueries("table", "etag", pgsql.NewOptions(pgsql.WithNotify("changes"))); got != nil {
+		t.Errorf("Queries = %#v, want none when the store publishes", got)
+	}
+
+	got := NotifyTriggerQueries("table", "etag", pgsql.NewOptions(pgsql.WithNotifyTriggers("changes")))
+	want := []string{
+		`CREATE OR REPLACE FUNCTION "table_notify"() RETURNS trigger AS $$ BEGIN IF TG_OP = 'DELETE' THEN ` +
+			`PERFORM pg_notify('changes', json_build_object('table', TG_TABLE_NAME, 'id', OLD.id::text, 'etag', OLD."etag", 'operation', 'delete')::text); ` +
+			`RETURN OLD; END IF; ` +
+			`PERFORM pg_notify('changes', json_build_object('table', TG_TABLE_NAME, 'id', NEW.id::text, 'etag', NEW."etag", 'operation', lower(TG_OP))::text); ` +
+			`RETURN NEW; END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS "table_notify" ON "table"`,
+		`CREATE TRIGGER "table_notify" AFTER INSERT OR UPDATE OR DELETE ON "table" FOR EACH ROW EXECUTE FUNCTION "table_notify"()`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries = %#v, want %#v", got, want)
+	}
+}
+
+func TestPrepareClear(t *testing.T) {
+	removal := goqu.Dialect("postgres").Delete("table").Where(goqu.C("name").Eq("John")).Returning(goqu.Star())
+
+	sqlStr, args, err := PrepareClear(context.Background(), "table", pgsql.NewOptions(), removal, "etag", "updated", goqu.I("removed.payload"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantSQL := `WITH removed AS (DELETE FROM "table" WHERE ("name" = $1) RETURNING *) SELECT CAST("id" AS VARCHAR), "etag" FROM "removed"`
+	if sqlStr != wantSQL {
+		t.Errorf("SQL = %s, want %s", sqlStr, wantSQL)
+	}
+	if wantArgs := []any{"John"}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+
+	sqlStr, _, err = PrepareClear(context.Background(), "table", pgsql.NewOptions(pgsql.WithHistory()), removal, "etag", "updated", goqu.I("removed.payload"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantSQL = `WITH removed AS (DELETE FROM "table" WHERE ("name" = $1) RETURNING *) ` +
+		`INSERT INTO "table_history" ("item_id", "operation", "actor", "old_etag", "old_updated", "old_payload") ` +
+		`SELECT CAST("removed"."id" AS VARCHAR), $2, $3, "removed"."etag", "removed"."updated", "removed"."payload" FROM "removed" ` +
+		`RETURNING "item_id", "old_etag"`
+	if sqlStr != wantSQL {
+		t.Errorf("SQL = %s, want %s", sqlStr, wantSQL)
+	}
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// OutboxQueries returns the statements creating the outbox table.
+func OutboxQueries(opts *pgsql.Options) []string {
+	if opts.OutboxTable == "" {
+		return nil
+	}
+	outbox := pq.QuoteIdentifier(opts.OutboxTable)
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (`+
+			`id BIGSERIAL PRIMARY KEY,`+
+			`aggregate VARCHAR NOT NULL,`+
+			`item_id VARCHAR NOT NULL,`+
+			`operation VARCHAR(6) NOT NULL,`+
+			`etag VARCHAR(32),`+
+			`actor VARCHAR,`+
+			`payload JSONB,`+
+			`created_at %[2]s NOT NULL DEFAULT now(),`+
+			`delivered_at %[2]s,`+
+			`attempts INTEGER NOT NULL DEFAULT 0,`+
+			`last_error VARCHAR)`, outbox, TimeType(opts)),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (id) WHERE delivered_at IS NULL`,
+			pq.QuoteIdentifier(opts.OutboxTable+"_pending_idx"), outbox),
+	}
+	return append(queries, TimeZoneQueries(opts.OutboxTable, opts, "created_at", "delivered_at")...)
+}
+
+// PrepareEvent returns the statement writing the outbox event of a change of
+// item.
+func PrepareEvent(ctx context.Context, table string, opts *pgsql.Options, operation string, item *resource.Item) (string, []any, error) {
+	payload, err := encodePayload(UTCPayload(opts, item.Payload))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return goqu.Dialect("postgres").Insert(opts.OutboxTable).Rows(goqu.Record{
+		"aggregate": table,
+		"item_id":   fmt.Sprint(item.ID),
+		"operation": operation,
+		"etag":      item.ETag,
+		"actor":     nullString(pgsql.ActorFromContext(ctx)),
+		"payload":   payload,
+	}).Prepared(true).ToSQL()
+}
+
+// WriteEvents writes the outbox events of changes without payload, such as
+// the removals of a Clear.
+func WriteEvents(ctx context.Context, q Querier, opts *pgsql.Options, changes ...pgsql.Change) error {
+	if opts.OutboxTable == "" || len(changes) == 0 {
+		return nil
+	}
+
+	rows := make([]any, len(changes))
+	for i, change := range changes {
+		rows[i] = goqu.Record{
+			"aggregate": change.Table,
+			"item_id":   change.ID,
+			"operation": change.Operation,
+			"etag":      change.ETag,
+			"actor":     nullString(pgsql.ActorFromContext(ctx)),
+		}
+	}
+
+	sqlStr, args, err := goqu.Dialect("postgres").Insert(opts.OutboxTable).Rows(rows...).Prepared(true).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, sqlStr, args...)
+	return err
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestOutboxQueries(t *testing.T) {
+	if got := OutboxQueries(pgsql.NewOptions()); got != nil {
+		t.Errorf("Queries = %#v, want none without outbox", got)
+	}
+
+	got := OutboxQueries(pgsql.NewOptions(pgsql.WithOutbox("")))
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "outbox" (id BIGSERIAL PRIMARY KEY,aggregate VARCHAR NOT NULL,item_id VARCHAR NOT NULL,` +
+			`operation VARCHAR(6) NOT NULL,etag VARCHAR(32),actor VARCHAR,payload JSONB,created_at TIMESTAMP NOT NULL DEFAULT now(),` +
+			`delivered_at TIMESTAMP,attempts INTEGER NOT NULL DEFAULT 0,last_error VARCHAR)`,
+		`CREATE INDEX IF NOT EXISTS "outbox_pending_idx" ON "outbox" (id) WHERE delivered_at IS NULL`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries = %#v, want %#v", got, want)
+	}
+}
+
+func TestPrepareEvent(t *testing.T) {
+	ctx := pgsql.NewActorContext(context.Background(), "alice")
+	item := &resource.Item{ID: 1, ETag: "abc", Payload: map[string]any{"id": 1, "name": "John"}}
+
+	sqlStr, args, err := PrepareEvent(ctx, "table", pgsql.NewOptions(pgsql.WithOutbox("events")), pgsql.OperationUpdate, item)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantSQL := `INSERT INTO "events" ("actor", "aggregate", "etag", "item_id", "operation", "payload") VALUES ($1, $2, $3, $4, $5, $6)`
+	if sqlStr != wantSQL {
+		t.Errorf("SQL = %s, want %s", sqlStr, wantSQL)
+	}
+	wantArgs := []any{"alice", "table", "abc", "1", "update", "{\"id\":1,\"name\":\"John\"}\n"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+}
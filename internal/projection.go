@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/schema/query"
+)
+
+// ProjectedField is a field of the payload built for a projection.
+type ProjectedField struct {
+	// Key is the name of the field in the projected payload. Aliases are
+	// left to rest-layer, which reads the value under its name.
+	Key string
+	// Path is the path of the projected value in the item.
+	Path []string
+	// Fields are the projected sub-fields of an object, nil when the whole
+	// value is projected.
+	Fields []*ProjectedField
+}
+
+// ProjectedFields returns the fields built for pj. Dotted names project the
+// sub-field within its parent object, unless they are aliased: the value is
+// then projected at the top, under the dotted name rest-layer reads before
+// aliasing it. Fields projected more than once are merged.
+func ProjectedFields(pj query.Projection) []*ProjectedField {
+	var fields []*ProjectedField
+	for _, pf := range pj {
+		fields = addProjected(fields, nil, pf)
+	}
+	return fields
+}
+
+func addProjected(fields []*ProjectedField, parent []string, pf query.ProjectionField) []*ProjectedField {
+	names := strings.Split(pf.Name, ".")
+	key, path, children := names[0], append(parent[:len(parent):len(parent)], names[0]), pf.Children
+	switch {
+	case pf.Alias != "":
+		key, path = pf.Name, append(parent[:len(parent):len(parent)], names...)
+	case len(names) > 1:
+		children = query.Projection{{Name: strings.Join(names[1:], "."), Params: pf.Params, Children: pf.Children}}
+	}
+
+	var field *ProjectedField
+	for _, f := range fields {
+		if f.Key == key && strings.Join(f.Path, ".") == strings.Join(path, ".") {
+			field = f
+			break
+		}
+	}
+	switch {
+	case field == nil:
+		field = &ProjectedField{Key: key, Path: path}
+		fields = append(fields, field)
+		if len(children) == 0 {
+			return fields
+		}
+	case field.Fields == nil || len(children) == 0:
+		// The whole value is already projected, or now is
+		field.Fields = nil
+		return fields
+	}
+
+	for _, child := range children {
+		if child.Name == "*" {
+			field.Fields = nil
+			return fields
+		}
+		field.Fields = addProjected(field.Fields, path, child)
+	}
+	return fields
+}
+
+// ProjectedObject returns the JSONB object of fields, reading the value at a
+// path with value.
+func ProjectedObject(fields []*ProjectedField, value func(path []string) exp.Expression) exp.Expression {
+	args := make([]any, 0, 2*len(fields))
+	for _, f := range fields {
+		args = append(args, goqu.L(pq.QuoteLiteral(f.Key)), ProjectedValue(f, value))
+	}
+	return goqu.L("jsonb_build_object?", args)
+}
+
+// ProjectedValue returns the JSONB value of field. A value projecting
+// sub-fields which is not an object, e.g. an array, is projected whole.
+func ProjectedValue(field *ProjectedField, value func(path []string) exp.Expression) exp.Expression {
+	if field.Fields == nil {
+		return value(field.Path)
+	}
+	v := value(field.Path)
+	return goqu.L("CASE WHEN jsonb_typeof(?) = 'object' THEN ? ELSE ? END", v, ProjectedObject(field.Fields, value), v)
+}
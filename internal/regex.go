@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxRepeat is the largest repetition count of PostgreSQL regular
+// expressions.
+const maxRepeat = 255
+
+// TranslateRegex translates re to a PostgreSQL advanced regular expression,
+// to be matched with ~, or with ~* when caseInsensitive is returned. The Go
+// semantics are kept: . does not match newlines unless the s flag is set, ^
+// and $ match at the ends of the text unless the m flag is set, and \d, \w
+// and \s are ASCII only. \b and \B use the word characters of the database
+// locale. Greediness is kept, although it does not change whether a text
+// matches.
+func TranslateRegex(re *regexp.Regexp) (pattern string, caseInsensitive bool, err error) {
+	ast, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return "", false, err
+	}
+
+	t := regexTranslator{expr: re.String(), foldAll: foldsAll(ast)}
+	if err := t.write(ast); err != nil {
+		return "", false, err
+	}
+
+	return t.b.String(), t.foldAll, nil
+}
+
+// foldsAll tells whether every literal and class of re is case folded, so
+// that re can be matched case insensitively as a whole.
+func foldsAll(re *syntax.Regexp) bool {
+	folded, found := true, false
+	var walk func(re *syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		switch re.Op {
+		case syntax.OpLiteral, syntax.OpCharClass:
+			found = true
+			folded = folded && re.Flags&syntax.FoldCase != 0
+		}
+		for _, sub := range re.Sub {
+			walk(sub)
+		}
+	}
+	walk(re)
+	return folded && found
+}
+
+type regexTranslator struct {
+	expr    string
+	foldAll bool
+	b       strings.Builder
+}
+
+func (t *regexTranslator) write(re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return fmt.Errorf("regex %s: pattern never matches", t.expr)
+
+	case syntax.OpEmptyMatch:
+
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			switch {
+			case re.Flags&syntax.FoldCase == 0:
+				t.b.WriteString(escapeRegexRune(r, false))
+			case t.foldAll:
+				// The parser keeps folded literals upper case
+				t.b.WriteString(escapeRegexRune(unicode.ToLower(r), false))
+			default:
+				t.writeFolded(r)
+			}
+		}
+
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return fmt.Errorf("regex %s: pattern never matches", t.expr)
+		}
+		t.writeClass(re.Rune)
+
+	case syntax.OpAnyCharNotNL:
+		t.b.WriteString(`[^\n]`)
+
+	case syntax.OpAnyChar:
+		// Without newline sensitive matching, . matches newlines
+		t.b.WriteString(".")
+
+	case syntax.OpBeginLine:
+		t.b.WriteString(`(?:^|(?<=\n))`)
+
+	case syntax.OpEndLine:
+		t.b.WriteString(`(?:$|(?=\n))`)
+
+	case syntax.OpBeginText:
+		t.b.WriteString("^")
+
+	case syntax.OpEndText:
+		t.b.WriteString("$")
+
+	case syntax.OpWordBoundary:
+		t.b.WriteString(`\y`)
+
+	case syntax.OpNoWordBoundary:
+		t.b.WriteString(`\Y`)
+
+	case syntax.OpCapture:
+		// Names are dropped, PostgreSQL has no named groups
+		t.b.WriteString("(")
+		if err := t.write(re.Sub[0]); err != nil {
+			return err
+		}
+		t.b.WriteString(")")
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if err := t.writeAtom(re.Sub[0]); err != nil {
+			return err
+		}
+		switch re.Op {
+		case syntax.OpStar:
+			t.b.WriteString("*")
+		case syntax.OpPlus:
+			t.b.WriteString("+")
+		case syntax.OpQuest:
+			t.b.WriteString("?")
+		case syntax.OpRepeat:
+			if re.Min > maxRepeat || re.Max > maxRepeat {
+				return fmt.Errorf("regex %s: repetition count exceeds the PostgreSQL limit of %d", t.expr, maxRepeat)
+			}
+			switch {
+			case re.Max == -1:
+				fmt.Fprintf(&t.b, "{%d,}", re.Min)
+			case re.Min == re.Max:
+				fmt.Fprintf(&t.b, "{%d}", re.Min)
+			default:
+				fmt.Fprintf(&t.b, "{%d,%d}", re.Min, re.Max)
+			}
+		}
+		if re.Flags&syntax.NonGreedy != 0 {
+			t.b.WriteString("?")
+		}
+
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpAlternate {
+				if err := t.writeGroup(sub); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := t.write(sub); err != nil {
+				return err
+			}
+		}
+
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i > 0 {
+				t.b.WriteString("|")
+			}
+			if err := t.write(sub); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("regex %s: unsupported construct %s", t.expr, re.Op)
+	}
+
+	return nil
+}
+
+// writeAtom writes re as the operand of a quantifier, grouping it when
+// needed.
+func (t *regexTranslator) writeAtom(re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL, syntax.OpCapture:
+		return t.write(re)
+	case syntax.OpLiteral:
+		if len(re.Rune) == 1 {
+			return t.write(re)
+		}
+	}
+	return t.writeGroup(re)
+}
+
+func (t *regexTranslator) writeGroup(re *syntax.Regexp) error {
+	t.b.WriteString("(?:")
+	if err := t.write(re); err != nil {
+		return err
+	}
+	t.b.WriteString(")")
+	return nil
+}
+
+// writeFolded writes r matching all its case variants.
+func (t *regexTranslator) writeFolded(r rune) {
+	variants := []rune{r}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		variants = append(variants, f)
+	}
+	if len(variants) == 1 {
+		t.b.WriteString(escapeRegexRune(r, false))
+		return
+	}
+
+	t.b.WriteString("[")
+	for _, v := range variants {
+		t.b.WriteString(escapeRegexRune(v, true))
+	}
+	t.b.WriteString("]")
+}
+
+// writeClass writes the class of the sorted rune ranges, as the negation of
+// its complement when it contains the first and the last rune.
+func (t *regexTranslator) writeClass(ranges []rune) {
+	negated := len(ranges) > 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if negated {
+		complement := make([]rune, 0, len(ranges))
+		for i := 1; i+1 < len(ranges); i += 2 {
+			complement = append(complement, ranges[i]+1, ranges[i+1]-1)
+		}
+		if len(complement) == 0 {
+			t.b.WriteString(".")
+			return
+		}
+		ranges = complement
+	}
+
+	t.b.WriteString("[")
+	if negated {
+		t.b.WriteString("^")
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		t.b.WriteString(escapeRegexRune(lo, true))
+		switch {
+		case hi == lo+1:
+			t.b.WriteString(escapeRegexRune(hi, true))
+		case hi > lo:
+			t.b.WriteString("-")
+			t.b.WriteString(escapeRegexRune(hi, true))
+		}
+	}
+	t.b.WriteString("]")
+}
+
+var regexEscapes = map[rune]string{
+	'\t': `\t`,
+	'\n': `\n`,
+	'\v': `\v`,
+	'\f': `\f`,
+	'\r': `\r`,
+}
+
+// escapeRegexRune returns r as a literal of a regular expression, or of a
+// bracket expression.
+func escapeRegexRune(r rune, bracket bool) string {
+	special := `\^$.[]|()*+?{}`
+	if bracket {
+		special = `\^-[]`
+	}
+
+	switch {
+	case strings.ContainsRune(special, r):
+		return `\` + string(r)
+	case regexEscapes[r] != "":
+		return regexEscapes[r]
+	case !unicode.IsPrint(r) && r <= 0xffff:
+		return fmt.Sprintf(`\u%04x`, r)
+	case !unicode.IsPrint(r):
+		return fmt.Sprintf(`\U%08x`, r)
+	}
+	return string(r)
+}
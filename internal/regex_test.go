@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTranslateRegex(t *testing.T) {
+	tests := []struct {
+		expr                string
+		wantPattern         string
+		wantCaseInsensitive bool
+		wantErr             string
+	}{
+		{expr: `John`, wantPattern: `John`},
+		{expr: `(?i)John`, wantPattern: `john`, wantCaseInsensitive: true},
+		{expr: `(?i:Jo)hn`, wantPattern: `[Jj][Oo]hn`},
+		{expr: `a.b`, wantPattern: `a[^\n]b`},
+		{expr: `(?s)a.b`, wantPattern: `a.b`},
+		{expr: `^a$`, wantPattern: `^a$`},
+		{expr: `(?m)^a$`, wantPattern: `(?:^|(?<=\n))a(?:$|(?=\n))`},
+		{expr: `\d+\w*\s?`, wantPattern: `[0-9]+[0-9A-Z_a-z]*[\t\n\f\r ]?`},
+		{expr: `[^a-c]`, wantPattern: `[^a-c]`},
+		{expr: `[\]\-^]`, wantPattern: `[\-\]\^]`},
+		{expr: `\bword\B`, wantPattern: `\yword\Y`},
+		{expr: `a+?b*?`, wantPattern: `a+?b*?`},
+		{expr: `(ab){2,3}|c{2,}`, wantPattern: `(ab){2,3}|c{2,}`},
+		{expr: `(?:ab)+c`, wantPattern: `(?:ab)+c`},
+		{expr: `x(?:a|b)y`, wantPattern: `x[ab]y`},
+		{expr: `x(?:ab|cd)y`, wantPattern: `x(?:ab|cd)y`},
+		{expr: `(?P<name>a)\.\$`, wantPattern: `(a)\.\$`},
+		{expr: `a{256}`, wantErr: `regex a{256}: repetition count exceeds the PostgreSQL limit of 255`},
+		{expr: `[^\x00-\x{10FFFF}]`, wantErr: `regex [^\x00-\x{10FFFF}]: pattern never matches`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pattern, caseInsensitive, err := TranslateRegex(regexp.MustCompile(tt.expr))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if pattern != tt.wantPattern || caseInsensitive != tt.wantCaseInsensitive {
+				t.Errorf("TranslateRegex = %s, %v, want %s, %v", pattern, caseInsensitive, tt.wantPattern, tt.wantCaseInsensitive)
+			}
+		})
+	}
+}
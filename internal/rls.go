@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// RowSecurityQueries returns the statements enabling row level security on
+// table and recreating its policies.
+func RowSecurityQueries(table string, rs *pgsql.RowSecurity) ([]string, error) {
+	if rs == nil {
+		return nil, nil
+	}
+
+	tableName := pq.QuoteIdentifier(table)
+	queries := []string{fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", tableName)}
+	if rs.Force {
+		queries = append(queries, fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY", tableName))
+	}
+
+	for _, p := range rs.Policies {
+		if p.Name == "" {
+			return nil, fmt.Errorf("policy name required")
+		}
+		if p.Using == "" && p.WithCheck == "" {
+			return nil, fmt.Errorf("policy %s: USING or WITH CHECK expression required", p.Name)
+		}
+
+		command := "ALL"
+		if p.Command != "" {
+			command = strings.ToUpper(p.Command)
+		}
+		switch command {
+		case "ALL", "SELECT", "INSERT", "UPDATE", "DELETE":
+		default:
+			return nil, fmt.Errorf("policy %s: unsupported command %s", p.Name, p.Command)
+		}
+
+		as := "PERMISSIVE"
+		if p.Restrictive {
+			as = "RESTRICTIVE"
+		}
+
+		roles := "PUBLIC"
+		if len(p.Roles) > 0 {
+			quoted := make([]string, len(p.Roles))
+			for i, role := range p.Roles {
+				quoted[i] = pq.QuoteIdentifier(role)
+			}
+			roles = strings.Join(quoted, ", ")
+		}
+
+		policyName := pq.QuoteIdentifier(p.Name)
+		create := fmt.Sprintf("CREATE POLICY %s ON %s AS %s FOR %s TO %s", policyName, tableName, as, command, roles)
+		if p.Using != "" {
+			create += " USING (" + p.Using + ")"
+		}
+		if p.WithCheck != "" {
+			create += " WITH CHECK (" + p.WithCheck + ")"
+		}
+
+		queries = append(queries,
+			fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s", policyName, tableName),
+			create,
+		)
+	}
+
+	return queries, nil
+}
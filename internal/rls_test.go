@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestRowSecurityQueries(t *testing.T) {
+	type test struct {
+		name       string
+		rs         *pgsql.RowSecurity
+		want       []string
+		wantErrStr string
+	}
+
+	tests := []test{
+		{
+			name: "disabled",
+			rs:   nil,
+			want: nil,
+		},
+		{
+			name: "tenant policy",
+			rs: &pgsql.RowSecurity{
+				Force: true,
+				Policies: []pgsql.Policy{
+					{
+						Name:      "tenant_isolation",
+						Using:     "payload->>'tenant' = " + pgsql.Setting("tenant"),
+						WithCheck: "payload->>'tenant' = " + pgsql.Setting("tenant"),
+					},
+				},
+			},
+			want: []string{
+				`ALTER TABLE "table" ENABLE ROW LEVEL SECURITY`,
+				`ALTER TABLE "table" FORCE ROW LEVEL SECURITY`,
+				`DROP POLICY IF EXISTS "tenant_isolation" ON "table"`,
+				`CREATE POLICY "tenant_isolation" ON "table" AS PERMISSIVE FOR ALL TO PUBLIC USING (payload->>'tenant' = current_setting('app.tenant', true)) WITH CHECK (payload->>'tenant' = current_setting('app.tenant', true))`,
+			},
+		},
+		{
+			name: "restrictive select for roles",
+			rs: &pgsql.RowSecurity{
+				Policies: []pgsql.Policy{
+					{
+						Name:        "readers",
+						Restrictive: true,
+						Command:     "select",
+						Roles:       []string{"reader", "auditor"},
+						Using:       "true",
+					},
+				},
+			},
+			want: []string{
+				`ALTER TABLE "table" ENABLE ROW LEVEL SECURITY`,
+				`DROP POLICY IF EXISTS "readers" ON "table"`,
+				`CREATE POLICY "readers" ON "table" AS RESTRICTIVE FOR SELECT TO "reader", "auditor" USING (true)`,
+			},
+		},
+		{
+			name: "unknown command",
+			rs: &pgsql.RowSecurity{
+				Policies: []pgsql.Policy{{Name: "p", Command: "TRUNCATE", Using: "true"}},
+			},
+			wantErrStr: "policy p: unsupported command TRUNCATE",
+		},
+		{
+			name: "missing expression",
+			rs: &pgsql.RowSecurity{
+				Policies: []pgsql.Policy{{Name: "p"}},
+			},
+			wantErrStr: "policy p: USING or WITH CHECK expression required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RowSecurityQueries("table", tt.rs)
+			if err != nil {
+				if err.Error() != tt.wantErrStr {
+					t.Fatalf("Error = %s, want %s", err.Error(), tt.wantErrStr)
+				}
+				return
+			}
+			if tt.wantErrStr != "" {
+				t.Fatalf("Error = nil, want %s", tt.wantErrStr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Queries = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionStatements(t *testing.T) {
+	got := SessionStatements(pgsql.SessionSettings{
+		Role:   "tenant_user",
+		Values: map[string]string{"user": "42", "tenant": "acme"},
+	})
+	want := []Statement{
+		{SQL: `SET LOCAL ROLE "tenant_user"`},
+		{SQL: "SELECT set_config($1, $2, true)", Args: []any{"app.tenant", "acme"}},
+		{SQL: "SELECT set_config($1, $2, true)", Args: []any{"app.user", "42"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Statements = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/schema"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// TextField is a searchable field of a schema, at a dotted path.
+type TextField struct {
+	Path string
+	Text *pgsql.Text
+}
+
+// TextFields returns the searchable fields of s, nested ones included,
+// sorted by path.
+func TextFields(s *schema.Schema) []TextField {
+	if s == nil {
+		return nil
+	}
+
+	var fields []TextField
+	for name, field := range s.Fields {
+		switch v := field.Validator.(type) {
+		case *pgsql.Text:
+			fields = append(fields, TextField{Path: name, Text: v})
+		case *schema.Object:
+			for _, nested := range TextFields(v.Schema) {
+				nested.Path = name + "." + nested.Path
+				fields = append(fields, nested)
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+
+	return fields
+}
+
+// SearchQueries returns the statements adding the search document of the
+// Text fields of s to table. text returns the SQL expression of the text of
+// the field at a dotted path.
+func SearchQueries(table string, s *schema.Schema, text func(path string) string) []string {
+	fields := TextFields(s)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		weight := field.Text.Weight
+		if weight == "" {
+			weight = "D"
+		}
+		parts[i] = fmt.Sprintf("setweight(to_tsvector(%s::regconfig, coalesce(%s, '')), %s)",
+			pq.QuoteLiteral(searchLanguage(field.Text.Language)), text(field.Path), pq.QuoteLiteral(weight))
+	}
+
+	return []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s tsvector GENERATED ALWAYS AS (%s) STORED",
+			pq.QuoteIdentifier(table), pq.QuoteIdentifier(pgsql.SearchColumn), strings.Join(parts, " || ")),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)",
+			pq.QuoteIdentifier(table+"_search_idx"), pq.QuoteIdentifier(table), pq.QuoteIdentifier(pgsql.SearchColumn)),
+	}
+}
+
+// JSONText returns the expression of the text at path in the JSONB column.
+func JSONText(column, path string) string {
+	return fmt.Sprintf("(%s #>> %s)", pq.QuoteIdentifier(column), pq.QuoteLiteral("{"+strings.ReplaceAll(path, ".", ",")+"}"))
+}
+
+// SearchValidator returns the Search validator of field of s, if any.
+func SearchValidator(s *schema.Schema, field string) (*pgsql.Search, bool) {
+	if s == nil {
+		return nil, false
+	}
+	f := s.GetField(field)
+	if f == nil {
+		return nil, false
+	}
+	search, ok := f.Validator.(*pgsql.Search)
+	return search, ok
+}
+
+// SearchMatch returns the expression matching the search document against
+// the websearch query value.
+func SearchMatch(search *pgsql.Search, value any) exp.Expression {
+	return goqu.L("? @@ ?", goqu.C(pgsql.SearchColumn), tsquery(search, value))
+}
+
+// SearchRank returns the rank of the search document against the websearch
+// query found in p for the search field, false if there is none.
+func SearchRank(search *pgsql.Search, field string, p query.Predicate) (exp.LiteralExpression, bool) {
+	value, ok := searchTerm(field, p)
+	if !ok {
+		return nil, false
+	}
+	return goqu.L("ts_rank(?, ?)", goqu.C(pgsql.SearchColumn), tsquery(search, value)), true
+}
+
+func tsquery(search *pgsql.Search, value any) exp.LiteralExpression {
+	return goqu.L("websearch_to_tsquery(?::regconfig, ?)", searchLanguage(search.Language), value)
+}
+
+// searchTerm returns the value the search field is filtered with, at the top
+// level of p or of its conjunctions.
+func searchTerm(field string, p query.Predicate) (any, bool) {
+	for _, e := range p {
+		switch t := e.(type) {
+		case *query.Equal:
+			if t.Field == field {
+				return t.Value, true
+			}
+		case *query.And:
+			if value, ok := searchTerm(field, query.Predicate(*t)); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func searchLanguage(language string) string {
+	if language == "" {
+		return pgsql.DefaultSearchLanguage
+	}
+	return language
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestSearchQueries(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"title": {Validator: &pgsql.Text{Language: "english", Weight: "A"}},
+			"body":  {Validator: &pgsql.Text{}},
+			"meta": {Validator: &schema.Object{Schema: &schema.Schema{
+				Fields: schema.Fields{
+					"tags": {Validator: &pgsql.Text{Weight: "B"}},
+				},
+			}}},
+			"count": {Validator: &schema.Integer{}},
+			"q":     pgsql.SearchField,
+		},
+	}
+
+	if got := SearchQueries("table", &schema.Schema{}, nil); got != nil {
+		t.Errorf("Queries = %#v, want none without text fields", got)
+	}
+
+	got := SearchQueries("table", sc, func(path string) string {
+		return JSONText("payload", path)
+	})
+	want := []string{
+		`ALTER TABLE "table" ADD COLUMN IF NOT EXISTS "search_vector" tsvector GENERATED ALWAYS AS (` +
+			`setweight(to_tsvector('simple'::regconfig, coalesce(("payload" #>> '{body}'), '')), 'D') || ` +
+			`setweight(to_tsvector('simple'::regconfig, coalesce(("payload" #>> '{meta,tags}'), '')), 'B') || ` +
+			`setweight(to_tsvector('english'::regconfig, coalesce(("payload" #>> '{title}'), '')), 'A')) STORED`,
+		`CREATE INDEX IF NOT EXISTS "table_search_idx" ON "table" USING GIN ("search_vector")`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+type queryKey struct{}
+
+// WithQuery returns a copy of ctx carrying q, the query the statements of the
+// operation are built from, reported with the slow ones.
+func WithQuery(ctx context.Context, q *query.Query) context.Context {
+	return context.WithValue(ctx, queryKey{}, q)
+}
+
+func queryFrom(ctx context.Context) *query.Query {
+	q, _ := ctx.Value(queryKey{}).(*query.Query)
+	return q
+}
+
+// Explain returns the statement returning the JSON plan of stmt, running it
+// when analyze is set.
+func Explain(stmt string, analyze bool) string {
+	if analyze {
+		return "EXPLAIN (ANALYZE, FORMAT JSON) " + stmt
+	}
+	return "EXPLAIN (FORMAT JSON) " + stmt
+}
+
+// sideEffects are the clauses and functions making a SELECT more than a read:
+// locking rows, notifying listeners or changing settings.
+var sideEffects = []string{" FOR UPDATE", " FOR NO KEY UPDATE", " FOR SHARE", " FOR KEY SHARE",
+	"PG_NOTIFY(", "SET_CONFIG(", "NEXTVAL(", "SETVAL("}
+
+// IsRead tells if stmt only reads rows, and may be run again to explain it.
+func IsRead(stmt string) bool {
+	fields := strings.Fields(stmt)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "SELECT") {
+		return false
+	}
+	normalized := strings.ToUpper(strings.Join(fields, " "))
+	for _, effect := range sideEffects {
+		if strings.Contains(normalized, effect) {
+			return false
+		}
+	}
+	return true
+}
+
+// capture records the statement of event when it is slow. Slow writes are
+// reported at once, slow reads by reportSlow: their rows may still be read,
+// leaving the connection busy until the operation is done.
+func (t *tracedQuerier) capture(ctx context.Context, event *pgsql.QueryEvent) {
+	if t.slow == nil || t.slow.Report == nil || event.Duration < t.slow.Threshold {
+		return
+	}
+	slow := &pgsql.SlowQuery{
+		Operation: event.Operation,
+		Table:     event.Table,
+		SQL:       event.SQL,
+		Args:      event.Args,
+		Query:     queryFrom(ctx),
+		Duration:  event.Duration,
+	}
+	if !IsRead(event.SQL) {
+		t.slow.Report(ctx, slow)
+		return
+	}
+	t.slowReads = append(t.slowReads, slow)
+}
+
+// explainSavepoint is the savepoint a slow read is explained in, within a
+// transaction.
+const explainSavepoint = "pgsql_explain"
+
+// reportSlow reports the slow reads of the operation, explaining them first
+// when it succeeded: the transaction of a failed operation is aborted.
+func (t *tracedQuerier) reportSlow(ctx context.Context, explain bool) {
+	reads := t.slowReads
+	t.slowReads = nil
+	for _, slow := range reads {
+		if explain {
+			slow.Plan, slow.Err = t.explain(ctx, slow)
+		}
+		t.slow.Report(ctx, slow)
+	}
+}
+
+// explain returns the plan of the slow read. Within a transaction, the read
+// is explained in a savepoint: a failure, e.g. a statement timeout of EXPLAIN
+// ANALYZE, must not abort the transaction of the operation.
+func (t *tracedQuerier) explain(ctx context.Context, slow *pgsql.SlowQuery) (plan []byte, err error) {
+	if t.inTx {
+		if _, err := t.q.ExecContext(ctx, "SAVEPOINT "+explainSavepoint); err != nil {
+			return nil, err
+		}
+		defer func() {
+			release := "RELEASE SAVEPOINT " + explainSavepoint
+			if err != nil {
+				release = "ROLLBACK TO SAVEPOINT " + explainSavepoint
+			}
+			if _, releaseErr := t.q.ExecContext(ctx, release); releaseErr != nil && err == nil {
+				plan, err = nil, releaseErr
+			}
+		}()
+	}
+
+	err = t.q.QueryRowContext(ctx, Explain(slow.SQL, t.slow.Analyze), slow.Args...).Scan(&plan)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// failingQuerier is a Querier whose QueryContext fails with err.
+type failingQuerier struct {
+	execQuerier
+	err error
+}
+
+func (q *failingQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, q.err
+}
+
+func TestExplain(t *testing.T) {
+	if got, want := Explain("SELECT 1", false), "EXPLAIN (FORMAT JSON) SELECT 1"; got != want {
+		t.Errorf("Explain = %q, want %q", got, want)
+	}
+	if got, want := Explain("SELECT 1", true), "EXPLAIN (ANALYZE, FORMAT JSON) SELECT 1"; got != want {
+		t.Errorf("Explain = %q, want %q", got, want)
+	}
+}
+
+func TestIsRead(t *testing.T) {
+	tests := map[string]bool{
+		`SELECT * FROM "users"`:                   true,
+		"\n  select count(*) FROM \"users\"":      true,
+		`INSERT INTO "users" ("id") VALUES ($1)`:  false,
+		`WITH "removed" AS (DELETE FROM "users")`: false,
+		`SELECT pg_notify($1, $2)`:                false,
+		`SELECT * FROM "users" FOR UPDATE`:        false,
+		"SELECT * FROM \"users\"\nfor  share":     false,
+		`SELECT set_config($1, $2, true)`:         false,
+		"":                                        false,
+	}
+	for stmt, want := range tests {
+		if got := IsRead(stmt); got != want {
+			t.Errorf("IsRead(%q) = %v, want %v", stmt, got, want)
+		}
+	}
+}
+
+func TestTracedQuerier_slow(t *testing.T) {
+	var reported []*pgsql.SlowQuery
+	report := func(ctx context.Context, slow *pgsql.SlowQuery) {
+		reported = append(reported, slow)
+	}
+	e := &Executor{Table: "users", Options: pgsql.NewOptions(pgsql.WithSlowQueries(pgsql.SlowQueries{Report: report}))}
+	q := &failingQuerier{execQuerier: execQuerier{result: driverResult(1)}, err: errors.New("failed")}
+	rq, _ := query.New("", `{"name": "john"}`, "", nil)
+	ctx := WithQuery(context.Background(), rq)
+
+	traced := e.trace(q, "Clear")
+	if _, err := traced.ExecContext(ctx, `DELETE FROM "users" WHERE "name" = $1`, "john"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("Reported %d slow statements, want the write at once", len(reported))
+	}
+	if slow := reported[0]; slow.Operation != "Clear" || slow.Table != "users" || slow.Query != rq ||
+		slow.SQL != `DELETE FROM "users" WHERE "name" = $1` || slow.Plan != nil || slow.Err != nil {
+		t.Errorf("Slow query = %+v", slow)
+	}
+
+	traced = e.trace(q, "Find")
+	if _, err := traced.QueryContext(ctx, `SELECT * FROM "users"`); err != q.err {
+		t.Fatalf("Error = %v, want %v", err, q.err)
+	}
+	if len(reported) != 1 {
+		t.Fatal("The slow read was reported before its operation was done")
+	}
+	traced.reportSlow(ctx, false)
+	if len(reported) != 2 {
+		t.Fatalf("Reported %d slow statements, want 2", len(reported))
+	}
+	if slow := reported[1]; slow.Operation != "Find" || slow.SQL != `SELECT * FROM "users"` || slow.Plan != nil || slow.Err != nil {
+		t.Errorf("Slow query = %+v", slow)
+	}
+	traced.reportSlow(ctx, false)
+	if len(reported) != 2 {
+		t.Error("The slow read was reported twice")
+	}
+
+	e.Options.SlowQueries.Threshold = time.Hour
+	traced = e.trace(q, "Update")
+	traced.ExecContext(ctx, `UPDATE "users" SET "name" = $1`, "jane")
+	if len(reported) != 2 {
+		t.Error("A fast statement was reported")
+	}
+}
+
+// explainConn is a driver connection failing the EXPLAIN statements and
+// recording the others.
+type explainConn struct {
+	stmts     []string
+	committed bool
+}
+
+func (c *explainConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *explainConn) Driver() driver.Driver                        { return nil }
+func (c *explainConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *explainConn) Close() error              { return nil }
+func (c *explainConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *explainConn) Commit() error             { c.committed = true; return nil }
+func (c *explainConn) Rollback() error           { return nil }
+
+func (c *explainConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.stmts = append(c.stmts, query)
+	if strings.HasPrefix(query, "EXPLAIN") {
+		return nil, errors.New("canceling statement due to statement timeout")
+	}
+	return noRows{}, nil
+}
+
+func (c *explainConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.stmts = append(c.stmts, query)
+	return driver.RowsAffected(0), nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return []string{"id"} }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
+
+func TestExecutor_RunTx_failingExplain(t *testing.T) {
+	conn := &explainConn{}
+	db := sql.OpenDB(conn)
+	defer db.Close()
+
+	var reported []*pgsql.SlowQuery
+	e := NewExecutor("users", db, pgsql.NewOptions(pgsql.WithSlowQueries(pgsql.SlowQueries{
+		Analyze: true,
+		Report: func(ctx context.Context, slow *pgsql.SlowQuery) {
+			reported = append(reported, slow)
+		},
+	})))
+
+	err := e.RunTx(context.Background(), "Find", func(ctx context.Context, q Querier) error {
+		rows, err := q.QueryContext(ctx, `SELECT * FROM "users"`)
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+	if err != nil {
+		t.Fatalf("The failing explain failed the operation: %v", err)
+	}
+	if !conn.committed {
+		t.Error("The transaction was not committed")
+	}
+
+	wantStmts := []string{
+		`SELECT * FROM "users"`,
+		"SAVEPOINT pgsql_explain",
+		`EXPLAIN (ANALYZE, FORMAT JSON) SELECT * FROM "users"`,
+		"ROLLBACK TO SAVEPOINT pgsql_explain",
+	}
+	if !reflect.DeepEqual(conn.stmts, wantStmts) {
+		t.Errorf("Statements = %q, want %q", conn.stmts, wantStmts)
+	}
+	if len(reported) != 1 || reported[0].Err == nil || reported[0].Plan != nil {
+		t.Errorf("Reported = %+v, want the read with the error of its explain", reported)
+	}
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// NotDeleted returns the expressions excluding soft deleted rows from a
+// read, unless the store keeps no soft deleted rows or ctx asks for them.
+// As-of reads need no filter, soft deletes being recorded in the history.
+func NotDeleted(ctx context.Context, opts *pgsql.Options) []exp.Expression {
+	if !opts.SoftDelete || pgsql.IncludeDeletedFromContext(ctx) {
+		return nil
+	}
+	if _, ok := pgsql.AsOfFromContext(ctx); ok {
+		return nil
+	}
+	return []exp.Expression{goqu.C(pgsql.DeletedColumn).IsNull()}
+}
+
+// SoftDeleteRecord is the SET clause soft deleting rows.
+func SoftDeleteRecord() goqu.Record {
+	return goqu.Record{pgsql.DeletedColumn: goqu.L("now()")}
+}
+
+// SoftDeleteQueries returns the statements adding the deletion column to an
+// existing table, and indexing it for Purge.
+func SoftDeleteQueries(table string, opts *pgsql.Options) []string {
+	if !opts.SoftDelete {
+		return nil
+	}
+	queries := []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", pq.QuoteIdentifier(table), pgsql.DeletedColumn, TimeType(opts)),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s) WHERE %s IS NOT NULL",
+			pq.QuoteIdentifier(table+"_"+pgsql.DeletedColumn+"_idx"), pq.QuoteIdentifier(table), pgsql.DeletedColumn, pgsql.DeletedColumn),
+	}
+	return append(queries, TimeZoneQueries(table, opts, pgsql.DeletedColumn)...)
+}
+
+// PurgeExpression matches the rows soft deleted for longer than retention
+// seconds.
+func PurgeExpression(retention float64) exp.Expression {
+	return goqu.L("? < now() - ? * INTERVAL '1 second'", goqu.C(pgsql.DeletedColumn), retention)
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+// TimeType returns the type of the time columns: TIMESTAMPTZ with time zones,
+// TIMESTAMP otherwise.
+func TimeType(opts *pgsql.Options) string {
+	if opts != nil && opts.TimeZone {
+		return "TIMESTAMPTZ"
+	}
+	return "TIMESTAMP"
+}
+
+// UTCPayload returns payload with its times, at any depth, in UTC when the
+// store uses time zones. payload itself is left untouched.
+func UTCPayload(opts *pgsql.Options, payload map[string]any) map[string]any {
+	if opts == nil || !opts.TimeZone {
+		return payload
+	}
+	return utcValue(payload).(map[string]any)
+}
+
+func utcValue(v any) any {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UTC()
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, v := range t {
+			out[k] = utcValue(v)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = utcValue(v)
+		}
+		return out
+	}
+	return v
+}
+
+// UTC returns v in UTC when it is a time read by a store using time zones.
+func UTC(opts *pgsql.Options, v any) any {
+	if t, ok := v.(time.Time); ok && opts != nil && opts.TimeZone {
+		return t.UTC()
+	}
+	return v
+}
+
+// TimeZoneQueries returns the statements converting the TIMESTAMP columns, or
+// arrays, of table to TIMESTAMPTZ when the store uses time zones. Their
+// values are taken as UTC times. Columns that are missing or already
+// converted are left untouched.
+func TimeZoneQueries(table string, opts *pgsql.Options, columns ...string) []string {
+	if opts == nil || !opts.TimeZone {
+		return nil
+	}
+
+	queries := make([]string, 0, len(columns))
+	for _, column := range columns {
+		queries = append(queries, fmt.Sprintf(`DO $$ BEGIN `+
+			`PERFORM set_config('TimeZone', 'UTC', true); `+
+			`IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = %[1]s AND column_name = %[2]s AND udt_name = 'timestamp') THEN `+
+			`ALTER TABLE %[3]s ALTER COLUMN %[4]s TYPE TIMESTAMPTZ USING %[4]s::TIMESTAMPTZ; `+
+			`ELSIF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = %[1]s AND column_name = %[2]s AND udt_name = '_timestamp') THEN `+
+			`ALTER TABLE %[3]s ALTER COLUMN %[4]s TYPE TIMESTAMPTZ[] USING %[4]s::TIMESTAMPTZ[]; `+
+			`END IF; END $$`,
+			pq.QuoteLiteral(table), pq.QuoteLiteral(column), pq.QuoteIdentifier(table), pq.QuoteIdentifier(column)))
+	}
+	return queries
+}
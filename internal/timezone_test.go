@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestUTCPayload(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*60*60)
+	payload := map[string]any{
+		"since": time.Date(2020, 1, 1, 2, 0, 0, 0, cest),
+		"address": map[string]any{
+			"moved": []any{time.Date(2021, 1, 1, 2, 0, 0, 0, cest)},
+		},
+		"name": "john",
+	}
+
+	if got := UTCPayload(&pgsql.Options{}, payload); !reflect.DeepEqual(got, payload) {
+		t.Errorf("UTCPayload() = %v, want %v", got, payload)
+	}
+
+	got := UTCPayload(&pgsql.Options{TimeZone: true}, payload)
+	want := map[string]any{
+		"since": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"address": map[string]any{
+			"moved": []any{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		"name": "john",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UTCPayload() = %v, want %v", got, want)
+	}
+	if payload["since"].(time.Time).Location() != cest {
+		t.Error("UTCPayload() modified the payload")
+	}
+}
+
+func TestColumnType_timeZone(t *testing.T) {
+	tests := []struct {
+		name  string
+		field schema.Field
+		opts  *pgsql.Options
+		want  string
+	}{
+		{"time", schema.Field{Validator: &schema.Time{}}, &pgsql.Options{}, "TIMESTAMP"},
+		{"time with time zones", schema.Field{Validator: &schema.Time{}, Required: true}, &pgsql.Options{TimeZone: true}, "TIMESTAMPTZ NOT NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ColumnType(&tt.field, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ColumnType() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	array := &schema.Array{Values: schema.Field{Validator: &schema.Time{}}}
+	if got := NativeArrayType(array, &pgsql.Options{TimeZone: true}); got != "TIMESTAMPTZ[]" {
+		t.Errorf("NativeArrayType() = %s, want TIMESTAMPTZ[]", got)
+	}
+}
+
+func TestTimeZoneQueries(t *testing.T) {
+	if queries := TimeZoneQueries("items", &pgsql.Options{}, "updated"); queries != nil {
+		t.Errorf("TimeZoneQueries() = %v, want none", queries)
+	}
+
+	queries := TimeZoneQueries("items", &pgsql.Options{TimeZone: true}, "updated", "since")
+	if len(queries) != 2 {
+		t.Fatalf("TimeZoneQueries() = %v, want 2 queries", queries)
+	}
+	for _, want := range []string{
+		`PERFORM set_config('TimeZone', 'UTC', true)`,
+		`table_name = 'items' AND column_name = 'since' AND udt_name = 'timestamp'`,
+		`ALTER TABLE "items" ALTER COLUMN "since" TYPE TIMESTAMPTZ USING "since"::TIMESTAMPTZ`,
+		`ALTER TABLE "items" ALTER COLUMN "since" TYPE TIMESTAMPTZ[] USING "since"::TIMESTAMPTZ[]`,
+	} {
+		if !strings.Contains(queries[1], want) {
+			t.Errorf("TimeZoneQueries() = %s, want %s", queries[1], want)
+		}
+	}
+}
@@ -6,7 +6,10 @@ import (
 	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
 )
 
-func PgtypeFromField(s *schema.Schema, parent, name string) string {
+// PgtypeFromField returns the type the texts of the field at name, within
+// parent, are cast to: the type of a PostgresTyper or, with time zones,
+// TIMESTAMPTZ for times. It is empty otherwise.
+func PgtypeFromField(s *schema.Schema, parent, name string, opts *pgsql.Options) string {
 	if s == nil {
 		return ""
 	}
@@ -18,8 +21,13 @@ func PgtypeFromField(s *schema.Schema, parent, name string) string {
 	field := s.GetField(name)
 	var pgtype string
 	if field != nil {
-		if postgresTyper, ok := field.Validator.(pgsql.PostgresTyper); ok {
-			pgtype = postgresTyper.PostgresType()
+		switch v := field.Validator.(type) {
+		case pgsql.PostgresTyper:
+			pgtype = v.PostgresType()
+		case *schema.Time:
+			if opts != nil && opts.TimeZone {
+				pgtype = TimeType(opts)
+			}
 		}
 	}
 	return pgtype
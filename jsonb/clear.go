@@ -2,54 +2,75 @@ package jsonb
 
 import (
 	"context"
-	"database/sql"
-	"log/slog"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/rs/rest-layer/schema"
 	"github.com/rs/rest-layer/schema/query"
 
 	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
 func (s store) Clear(ctx context.Context, q *query.Query) (count int, err error) {
-	builder := s.dialect.Delete(s.table)
+	defer internal.Measure(s.opts, s.table, "Clear", time.Now(), &err)
 
-	err = buildDeleteWheres(s.schema, q, builder)
+	ctx = internal.WithQuery(ctx, q)
+	var removal exp.AppendableExpression
+	if s.opts.SoftDelete {
+		builder := s.dialect.Update(s.table)
+		err = buildSoftDeleteWheres(s.schema, s.opts, q, builder)
+		removal = builder.Returning(goqu.Star())
+	} else {
+		builder := s.dialect.Delete(s.table)
+		err = buildDeleteWheres(s.schema, s.opts, q, builder)
+		removal = builder.Returning(goqu.Star())
+	}
 	if err != nil {
 		return
 	}
 
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	payload := goqu.L(`? || jsonb_build_object('id', ?)`, goqu.I("removed.payload"), goqu.I("removed.id"))
+	sqlStr, args, err := internal.PrepareClear(ctx, s.table, s.opts, removal, "etag", "updated", payload)
 	if err != nil {
 		return
 	}
 
-	slog.DebugContext(ctx, "pgsql.Clear", "sql", sqlStr, "args", args)
+	err = s.exec.RunWrite(ctx, "Clear", func(ctx context.Context, q internal.Querier) error {
+		changes, err := internal.ExecClear(ctx, q, s.table, sqlStr, args)
+		if err != nil {
+			return err
+		}
+		count = len(changes)
 
-	tx := pgsql.TransactionFromContext(ctx)
-	var res sql.Result
-	if tx != nil {
-		res, err = tx.ExecContext(ctx, sqlStr, args...)
-	} else {
-		res, err = s.db.ExecContext(ctx, sqlStr, args...)
-	}
+		if err := internal.WriteEvents(ctx, q, s.opts, changes...); err != nil {
+			return err
+		}
+		return internal.Publish(ctx, q, s.opts, changes...)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	cnt, err := res.RowsAffected()
+	return count, nil
+}
+
+func buildDeleteWheres(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *goqu.DeleteDataset) error {
+	expressions, err := predicteToExpressions("", s, opts, q.Predicate)
 	if err != nil {
-		return 0, err
+		return err
 	}
-
-	return int(cnt), err
+	*builder = *builder.Where(expressions...)
+	return nil
 }
-func buildDeleteWheres(s *schema.Schema, q *query.Query, builder *goqu.DeleteDataset) error {
-	expressions, err := predicteToExpressions("", s, q.Predicate)
+
+func buildSoftDeleteWheres(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *goqu.UpdateDataset) error {
+	expressions, err := predicteToExpressions("", s, opts, q.Predicate)
 	if err != nil {
 		return err
 	}
-	*builder = *builder.Where(expressions...)
+	expressions = append(expressions, goqu.C(pgsql.DeletedColumn).IsNull())
+	*builder = *builder.Set(internal.SoftDeleteRecord()).Where(expressions...)
 	return nil
 }
@@ -2,15 +2,14 @@ package jsonb
 
 import (
 	"context"
-	"database/sql"
-	"log/slog"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/rs/rest-layer/resource"
-	"github.com/rs/rest-layer/rest"
 
 	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
 func prepareDelete(item *resource.Item) exp.Expression {
@@ -22,37 +21,43 @@ func buildDelete(item *resource.Item, builder *goqu.DeleteDataset) {
 	*builder = *builder.Where(exp)
 }
 
-func (s store) Delete(ctx context.Context, item *resource.Item) error {
-	builder := s.dialect.Delete(s.table)
-
-	buildDelete(item, builder)
-
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
-	if err != nil {
-		return err
-	}
+func buildSoftDelete(item *resource.Item, builder *goqu.UpdateDataset) {
+	*builder = *builder.Set(internal.SoftDeleteRecord()).Where(prepareDelete(item), goqu.C(pgsql.DeletedColumn).IsNull())
+}
 
-	slog.DebugContext(ctx, "psql.Delete", sqlStr, args)
+func (s store) Delete(ctx context.Context, item *resource.Item) (err error) {
+	defer internal.Measure(s.opts, s.table, "Delete", time.Now(), &err)
 
-	tx := pgsql.TransactionFromContext(ctx)
-	var affect sql.Result
-	if tx != nil {
-		affect, err = tx.ExecContext(ctx, sqlStr, args...)
+	var sqlStr string
+	var args []any
+	if s.opts.SoftDelete {
+		builder := s.dialect.Update(s.table)
+		buildSoftDelete(item, builder)
+		sqlStr, args, err = builder.Prepared(true).ToSQL()
 	} else {
-		affect, err = s.db.ExecContext(ctx, sqlStr, args...)
+		builder := s.dialect.Delete(s.table)
+		buildDelete(item, builder)
+		sqlStr, args, err = builder.Prepared(true).ToSQL()
 	}
 	if err != nil {
 		return err
 	}
 
-	count, err := affect.RowsAffected()
-	if err != nil {
-		return err
-	}
+	return s.exec.RunWrite(ctx, "Delete", func(ctx context.Context, q internal.Querier) error {
+		affect, err := q.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
 
-	if count != 1 {
-		return rest.ErrPreconditionFailed
-	}
+		count, err := affect.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return internal.Conflict(ctx, q, s.table, "etag", item.ID, internal.Live(s.opts)...)
+		}
 
-	return nil
+		return internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationDelete, item, nil)
+	})
 }
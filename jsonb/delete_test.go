@@ -59,3 +59,27 @@ func Test_buildDelete(t *testing.T) {
 		})
 	}
 }
+
+func Test_buildSoftDelete(t *testing.T) {
+	item := resource.Item{
+		ID:   "1",
+		ETag: "ABCDEF",
+	}
+
+	builder := goqu.Update("table")
+	buildSoftDelete(&item, builder)
+	sql, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		t.Fatalf("buildSoftDelete() error = %v", err)
+	}
+
+	wantSQL := `UPDATE "table" SET "deleted_at"=now() WHERE (((id = ?) AND (etag = ?)) AND ("deleted_at" IS NULL))`
+	if sql != wantSQL {
+		t.Errorf("buildSoftDelete() = %v, want %v", sql, wantSQL)
+	}
+
+	wantArgs := []interface{}{"1", "ABCDEF"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("buildSoftDelete() args = %v, want %v", args, wantArgs)
+	}
+}
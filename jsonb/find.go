@@ -2,56 +2,25 @@ package jsonb
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log/slog"
-	"reflect"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
-	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/rs/rest-layer/resource"
 	"github.com/rs/rest-layer/schema"
 	"github.com/rs/rest-layer/schema/query"
 
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
 	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
-func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, error) {
-	builder := s.dialect.From(s.table)
-	buildSelects(q, builder)
-	err := buildWheres(s.schema, q, builder)
-	if err != nil {
-		return nil, errors.Wrapf(err, "predicate: %v", q.Predicate)
-	}
-
-	buildSorts(s.schema, q, builder)
-	buildPagination(q, builder)
-
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
-	if err != nil {
-		return nil, errors.Wrapf(err, "predicate: %v", q.Predicate)
-	}
-	sqlStr = strings.ReplaceAll(sqlStr, "$$", "?")
-
-	slog.DebugContext(ctx, "pgsql.Find", "sql", sqlStr, "args", args)
-
-	rows, err := s.db.QueryContext(ctx, sqlStr, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	// result mapping
-	cols, err := rows.Columns()
-	if err != nil {
-		return nil, err
-	}
+func (s store) Find(ctx context.Context, q *query.Query) (list *resource.ItemList, err error) {
+	defer internal.Measure(s.opts, s.table, "Find", time.Now(), &err)
 
 	limit := 10
 	if q.Window != nil {
@@ -64,72 +33,52 @@ func (s store) Find(ctx context.Context, q *query.Query) (*resource.ItemList, er
 		Items: []*resource.Item{},
 	}
 
-	for rows.Next() {
-		rowVals := make([]any, len(cols))
-		rowValPtrs := make([]any, len(cols))
-
-		for i := range cols {
-			rowValPtrs[i] = &rowVals[i]
-		}
-
-		err := rows.Scan(rowValPtrs...)
-		if err != nil {
-			return nil, err
-		}
-
-		var ID any
-		var etag string
-		var updated time.Time
-		payload := make(map[string]any)
-
-		for i, v := range rowVals {
-			b, ok := v.([]byte)
-			if ok {
-				v = string(b)
-			}
-
-			switch cols[i] {
-			case "id":
-				ID = v
-				switch t := v.(type) {
-				case int64:
-					ID = strconv.Itoa(int(t))
-				}
-			case "etag":
-				etag = v.(string)
-			case "updated":
-				updated = v.(time.Time)
-			case "payload":
-				if err := json.Unmarshal([]byte(v.(string)), &payload); err != nil {
-					return nil, err
-				}
-			}
-		}
-
-		payload["id"] = ID
-
-		item := &resource.Item{
-			ID:      ID,
-			ETag:    etag,
-			Updated: updated,
-			Payload: payload,
-		}
-		internal.FixSchemaTypes(s.schema, item.Payload)
+	err = s.reduce(ctx, q, func(item *resource.Item) error {
 		result.Items = append(result.Items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
 }
 
-func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *resource.Item) error) error {
-	builder := s.dialect.From(s.table)
+func (s store) FindEmbedded(ctx context.Context, q *query.Query) (*resource.ItemList, error) {
+	return s.Find(pgsql.EmbedReferences(ctx), q)
+}
+
+func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *resource.Item) error) (err error) {
+	defer internal.Measure(s.opts, s.table, "Reduce", time.Now(), &err)
+
+	return s.reduce(ctx, q, reducer)
+}
+
+// reduce is Reduce, without recording the operation, for Find.
+func (s store) reduce(ctx context.Context, q *query.Query, reducer func(item *resource.Item) error) error {
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
+	if err != nil {
+		return err
+	}
+	builder := s.dialect.From(source)
 	buildSelects(q, builder)
-	err := buildWheres(s.schema, q, builder)
+	if pgsql.EmbedsReferences(ctx) {
+		internal.Embed(builder, s.table, s.schema, q.Projection, s.opts.EmbeddedReferences, func(name string) exp.Expression {
+			return goqu.L("?->>?", goqu.I(s.table+".payload"), name)
+		})
+	}
+	err = buildWheres(s.schema, s.opts, q, builder)
 	if err != nil {
 		return errors.Wrapf(err, "predicate: %v", q.Predicate)
 	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
 
-	buildSorts(s.schema, q, builder)
+	if err := buildSorts(s.schema, s.opts, q, builder); err != nil {
+		return err
+	}
 	buildPagination(q, builder)
 
 	sqlStr, args, err := builder.Prepared(true).ToSQL()
@@ -138,15 +87,22 @@ func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *re
 	}
 	sqlStr = strings.ReplaceAll(sqlStr, "$$", "?")
 
-	slog.DebugContext(ctx, "pgsql.Find", "sql", sqlStr, "args", args)
+	return s.exec.Run(ctx, "Find", func(ctx context.Context, querier internal.Querier) error {
+		rows, err := querier.QueryContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
 
-	rows, err := s.db.QueryContext(ctx, sqlStr, args...)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
+		if err := s.scanItems(rows, reducer); err != nil {
+			return err
+		}
+		return rows.Err()
+	})
+}
 
-	// result mapping
+// scanItems maps every row to a resource.Item and hands it to fn.
+func (s store) scanItems(rows *sql.Rows, fn func(item *resource.Item) error) error {
 	cols, err := rows.Columns()
 	if err != nil {
 		return err
@@ -169,6 +125,7 @@ func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *re
 		var etag string
 		var updated time.Time
 		payload := make(map[string]any)
+		embedded := make(map[string]any)
 
 		for i, v := range rowVals {
 			b, ok := v.([]byte)
@@ -178,21 +135,26 @@ func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *re
 
 			switch cols[i] {
 			case "id":
-				ID = v
-				switch t := v.(type) {
-				case int64:
-					ID = strconv.Itoa(int(t))
+				if ID, err = internal.IDs(s.schema, s.opts).ParseID(v); err != nil {
+					return err
 				}
 			case "etag":
 				etag = v.(string)
 			case "updated":
-				updated = v.(time.Time)
+				updated = internal.UTC(s.opts, v).(time.Time)
 			case "payload":
 				if err := json.Unmarshal([]byte(v.(string)), &payload); err != nil {
 					return err
 				}
+			default:
+				if name, ok := strings.CutPrefix(cols[i], internal.EmbeddedPrefix); ok {
+					embedded[name] = v
+				}
 			}
 		}
+		if err := internal.MergeEmbedded(payload, embedded); err != nil {
+			return err
+		}
 
 		payload["id"] = ID
 
@@ -204,8 +166,7 @@ func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *re
 		}
 		internal.FixSchemaTypes(s.schema, item.Payload)
 
-		err = reducer(item)
-		if err != nil {
+		if err := fn(item); err != nil {
 			return err
 		}
 	}
@@ -213,25 +174,33 @@ func (s store) Reduce(ctx context.Context, q *query.Query, reducer func(item *re
 	return nil
 }
 
-func (s store) Count(ctx context.Context, q *query.Query) (int, error) {
-	builder := s.dialect.From(s.table).Select(goqu.COUNT(goqu.Star()))
+func (s store) Count(ctx context.Context, q *query.Query) (total int, err error) {
+	defer internal.Measure(s.opts, s.table, "Count", time.Now(), &err)
 
-	err := buildWheres(s.schema, q, builder)
+	ctx = internal.WithQuery(ctx, q)
+	source, err := s.source(ctx)
 	if err != nil {
 		return 0, err
 	}
+	builder := s.dialect.From(source).Select(goqu.COUNT(goqu.Star()))
 
-	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	err = buildWheres(s.schema, s.opts, q, builder)
 	if err != nil {
 		return 0, err
 	}
+	if notDeleted := internal.NotDeleted(ctx, s.opts); len(notDeleted) > 0 {
+		builder = builder.Where(notDeleted...)
+	}
 
-	slog.DebugContext(ctx, "pqsql.Count", "sql", sqlStr, "args", args)
-
-	row := s.db.QueryRowContext(ctx, sqlStr, args...)
+	sqlStr, args, err := builder.Prepared(true).ToSQL()
+	if err != nil {
+		return 0, err
+	}
 
 	var count int
-	err = row.Scan(&count)
+	err = s.exec.Run(ctx, "Count", func(ctx context.Context, querier internal.Querier) error {
+		return querier.QueryRowContext(ctx, sqlStr, args...).Scan(&count)
+	})
 
 	return count, err
 }
@@ -243,27 +212,27 @@ func buildSelects(q *query.Query, builder *goqu.SelectDataset) {
 		return
 	}
 
-	prefixExprs := []any{goqu.C("id"), goqu.C("updated"), goqu.C("etag")}
-	var exprs []any
-	for _, field := range pj {
-		if field.Name == "id" {
+	var fields []*internal.ProjectedField
+	for _, field := range internal.ProjectedFields(pj) {
+		if field.Key == "id" && len(field.Path) == 1 {
 			continue
 		}
-
-		// Extract only top level field name
-		name := field.Name
-		fname := strings.Split(field.Name, ".")
-		if len(fname) > 1 {
-			name = fname[0]
-		}
-		exprs = append(exprs, goqu.L("'"+name+"'"), goqu.L(`payload->?`, goqu.V(name)))
+		fields = append(fields, field)
 	}
 
-	prefixExprs = append(prefixExprs,
-		goqu.L("jsonb_strip_nulls(jsonb_build_object?)", exprs).As("payload"),
+	*builder = *builder.Select(
+		goqu.C("id"), goqu.C("updated"), goqu.C("etag"),
+		goqu.L("jsonb_strip_nulls(?)", internal.ProjectedObject(fields, payloadPath)).As("payload"),
 	)
+}
 
-	*builder = *builder.Select(prefixExprs...)
+// payloadPath returns the JSONB value at path in the payload.
+func payloadPath(path []string) exp.Expression {
+	args := make([]any, len(path))
+	for i, name := range path {
+		args[i] = name
+	}
+	return goqu.L("payload"+strings.Repeat("->?", len(path)), args...)
 }
 
 func hasStar(pj query.Projection) bool {
@@ -281,8 +250,8 @@ func match(pj query.Projection, predicate func(pf query.ProjectionField) bool) b
 	return false
 }
 
-func buildWheres(s *schema.Schema, q *query.Query, builder *goqu.SelectDataset) error {
-	expressions, err := predicteToExpressions("", s, q.Predicate)
+func buildWheres(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *goqu.SelectDataset) error {
+	expressions, err := predicteToExpressions("", s, opts, q.Predicate)
 	if err != nil {
 		return err
 	}
@@ -290,15 +259,33 @@ func buildWheres(s *schema.Schema, q *query.Query, builder *goqu.SelectDataset)
 	return nil
 }
 
-func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (expressions []exp.Expression, err error) {
+func predicteToExpressions(parent string, s *schema.Schema, opts *pgsql.Options, q query.Predicate) (expressions []exp.Expression, err error) {
 	for _, e := range q {
+		if expr, ok := internal.GeoPredicate(e, func(field string) exp.Expression {
+			return pointField(parent, field)
+		}); ok {
+			expressions = append(expressions, expr)
+			continue
+		}
+
+		expr, ok, err := internal.ArrayPredicate(s, e, func(field string) exp.Expression {
+			return postgresJsonbSupport(parent, field, true)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			expressions = append(expressions, expr)
+			continue
+		}
+
 		switch t := e.(type) {
 
 		case *query.And:
 			var exprs []exp.Expression
 			for _, subExp := range *t {
 				var sube []exp.Expression
-				sube, err = predicteToExpressions(parent, s, query.Predicate{subExp})
+				sube, err = predicteToExpressions(parent, s, opts, query.Predicate{subExp})
 				if err != nil {
 					return nil, err
 				}
@@ -310,7 +297,7 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			var exprs []exp.Expression
 			for _, subExp := range *t {
 				var sube []exp.Expression
-				sube, err = predicteToExpressions(parent, s, query.Predicate{subExp})
+				sube, err = predicteToExpressions(parent, s, opts, query.Predicate{subExp})
 				if err != nil {
 					return nil, err
 				}
@@ -325,57 +312,30 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			expressions = append(expressions, postgresJsonbSupport(parent, t.Field, false).NotIn(t.Values))
 
 		case *query.Equal:
-			isSchemaArray := false
-			ff := s.GetField(parent + t.Field)
-			if ff != nil {
-				if _, ok := ff.Validator.(*schema.Array); ok {
-					isSchemaArray = true
-				}
+			if search, ok := internal.SearchValidator(s, t.Field); ok && parent == "" {
+				expressions = append(expressions, internal.SearchMatch(search, t.Value))
+				continue
 			}
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
+
 			var expr exp.Expression
-			if pgtype != "" {
-				if isSchemaArray {
-					expr = goqu.L("? $$| ?", goqu.Cast(postgresJsonbSupport(parent, t.Field, true), pgtype), pq.Array(convertToArray(t.Value)))
-				} else {
-					expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Eq(t.Value)
-				}
+			if pgtype := internal.PgtypeFromField(s, parent, t.Field, opts); pgtype != "" {
+				expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Eq(t.Value)
 			} else {
-				if isSchemaArray {
-					expr = goqu.L("? $$| ?", postgresJsonbSupport(parent, t.Field, true), pq.Array(convertToArray(t.Value)))
-				} else {
-					expr = postgresJsonbSupport(parent, t.Field, false).Eq(t.Value)
-				}
+				expr = postgresJsonbSupport(parent, t.Field, false).Eq(t.Value)
 			}
 			expressions = append(expressions, expr)
 
 		case *query.NotEqual:
-			isSchemaArray := false
-			ff := s.GetField(parent + t.Field)
-			if ff != nil {
-				if _, ok := ff.Validator.(*schema.Array); ok {
-					isSchemaArray = true
-				}
-			}
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
 			var expr exp.Expression
-			if pgtype != "" {
-				if isSchemaArray {
-					expr = goqu.L("NOT (? $$| ?)", goqu.Cast(postgresJsonbSupport(parent, t.Field, true), pgtype), pq.Array(convertToArray(t.Value)))
-				} else {
-					expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Neq(t.Value)
-				}
+			if pgtype := internal.PgtypeFromField(s, parent, t.Field, opts); pgtype != "" {
+				expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Neq(t.Value)
 			} else {
-				if isSchemaArray {
-					expr = goqu.L("NOT (? $$| ?)", postgresJsonbSupport(parent, t.Field, true), pq.Array(convertToArray(t.Value)))
-				} else {
-					expr = postgresJsonbSupport(parent, t.Field, false).Neq(t.Value)
-				}
+				expr = postgresJsonbSupport(parent, t.Field, false).Neq(t.Value)
 			}
 			expressions = append(expressions, expr)
 
 		case *query.GreaterThan:
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
+			pgtype := internal.PgtypeFromField(s, parent, t.Field, opts)
 			var expr exp.Expression
 			if pgtype != "" {
 				expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Gt(t.Value)
@@ -385,7 +345,7 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			expressions = append(expressions, expr)
 
 		case *query.GreaterOrEqual:
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
+			pgtype := internal.PgtypeFromField(s, parent, t.Field, opts)
 			var expr exp.Expression
 			if pgtype != "" {
 				expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Gte(t.Value)
@@ -395,7 +355,7 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			expressions = append(expressions, expr)
 
 		case *query.LowerThan:
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
+			pgtype := internal.PgtypeFromField(s, parent, t.Field, opts)
 			var expr exp.Expression
 			if pgtype != "" {
 				expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Lt(t.Value)
@@ -405,7 +365,7 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			expressions = append(expressions, expr)
 
 		case *query.LowerOrEqual:
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
+			pgtype := internal.PgtypeFromField(s, parent, t.Field, opts)
 			var expr exp.Expression
 			if pgtype != "" {
 				expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).Lte(t.Value)
@@ -415,57 +375,24 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			expressions = append(expressions, expr)
 
 		case *query.Regex:
-			re := regexp.MustCompile(`(\(.*\))?(.*)`)
-			matches := re.FindStringSubmatch(t.Value.String())
-			caseInsensitive := false
-			value := t.Value.String()
-			if len(matches) == 3 {
-				flags := matches[1]
-				flags = strings.ReplaceAll(flags, "(", "")
-				flags = strings.ReplaceAll(flags, ")", "")
-				for _, c := range flags {
-					switch c {
-					case 'i':
-						caseInsensitive = true
-					case 'm':
-					case 's':
-					default:
-						return nil, fmt.Errorf("unsupported regex flag %c", c)
-					}
-				}
-
-				value = matches[2]
+			pattern, caseInsensitive, err := internal.TranslateRegex(t.Value)
+			if err != nil {
+				return nil, err
+			}
+			var field exp.Likeable = postgresJsonbSupport(parent, t.Field, false)
+			if pgtype := internal.PgtypeFromField(s, parent, t.Field, opts); pgtype != "" {
+				field = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype)
 			}
-			pgtype := internal.PgtypeFromField(s, parent, t.Field)
 			var expr exp.Expression
-			if pgtype != "" {
-				if t.Negated {
-					if caseInsensitive {
-						expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).RegexpNotILike(value)
-					} else {
-						expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).RegexpNotLike(value)
-					}
-				} else {
-					if caseInsensitive {
-						expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).RegexpILike(value)
-					} else {
-						expr = goqu.Cast(postgresJsonbSupport(parent, t.Field, false), pgtype).RegexpLike(value)
-					}
-				}
-			} else {
-				if t.Negated {
-					if caseInsensitive {
-						expr = postgresJsonbSupport(parent, t.Field, false).RegexpNotILike(value)
-					} else {
-						expr = postgresJsonbSupport(parent, t.Field, false).RegexpNotLike(value)
-					}
-				} else {
-					if caseInsensitive {
-						expr = postgresJsonbSupport(parent, t.Field, false).RegexpILike(value)
-					} else {
-						expr = postgresJsonbSupport(parent, t.Field, false).RegexpLike(value)
-					}
-				}
+			switch {
+			case t.Negated && caseInsensitive:
+				expr = field.RegexpNotILike(pattern)
+			case t.Negated:
+				expr = field.RegexpNotLike(pattern)
+			case caseInsensitive:
+				expr = field.RegexpILike(pattern)
+			default:
+				expr = field.RegexpLike(pattern)
 			}
 			expressions = append(expressions, expr)
 
@@ -481,7 +408,7 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 			exprs := make([]exp.Expression, 0)
 			for _, p := range t.Exps {
 				var sube []exp.Expression
-				sube, err = predicteToExpressions(t.Field, s, query.Predicate{p})
+				sube, err = predicteToExpressions(t.Field, s, opts, query.Predicate{p})
 				if err != nil {
 					return nil, err
 				}
@@ -498,6 +425,15 @@ func predicteToExpressions(parent string, s *schema.Schema, q query.Predicate) (
 	return
 }
 
+// pointField returns the point of the lat/lng object of field, in the
+// payload or, within an $elemMatch, in the parent array element.
+func pointField(parent, field string) exp.Expression {
+	if parent == "" {
+		parent = "payload"
+	}
+	return goqu.L(internal.JSONPoint(parent, field))
+}
+
 func postgresJsonbSupport(parent, field string, asJSOBN bool) exp.LiteralExpression {
 	if field == "id" {
 		return goqu.L(field)
@@ -535,8 +471,32 @@ func postgresJsonbSupport(parent, field string, asJSOBN bool) exp.LiteralExpress
 	return goqu.L(literalText, exprs...)
 }
 
-func prepareSorts(s *schema.Schema, q *query.Query) (orders []exp.OrderedExpression) {
+// prepareSorts returns the orders of the sort of q.
+func prepareSorts(s *schema.Schema, opts *pgsql.Options, q *query.Query) (orders []exp.OrderedExpression, err error) {
 	for _, field := range q.Sort {
+		if search, ok := internal.SearchValidator(s, field.Name); ok {
+			if rank, ok := internal.SearchRank(search, field.Name, q.Predicate); ok {
+				if field.Reversed {
+					orders = append(orders, rank.Desc())
+				} else {
+					orders = append(orders, rank.Asc())
+				}
+			}
+			continue
+		}
+		if internal.PointField(s, field.Name) {
+			distance, err := internal.GeoDistance(field.Name, q.Predicate, pointField("", field.Name))
+			if err != nil {
+				return nil, err
+			}
+			if field.Reversed {
+				orders = append(orders, distance.Desc())
+			} else {
+				orders = append(orders, distance.Asc())
+			}
+			continue
+		}
+
 		expr := goqu.L(field.Name)
 		switch field.Name {
 		case "id":
@@ -546,7 +506,7 @@ func prepareSorts(s *schema.Schema, q *query.Query) (orders []exp.OrderedExpress
 			expr = postgresJsonbSupport("", field.Name, false)
 		}
 
-		pgtype := internal.PgtypeFromField(s, "", field.Name)
+		pgtype := internal.PgtypeFromField(s, "", field.Name, opts)
 		if field.Reversed {
 			if pgtype != "" {
 				orders = append(orders, exp.OrderedExpression(goqu.Cast(expr, pgtype).Desc()))
@@ -564,11 +524,15 @@ func prepareSorts(s *schema.Schema, q *query.Query) (orders []exp.OrderedExpress
 	return
 }
 
-func buildSorts(s *schema.Schema, q *query.Query, builder *goqu.SelectDataset) {
-	orders := prepareSorts(s, q)
+func buildSorts(s *schema.Schema, opts *pgsql.Options, q *query.Query, builder *goqu.SelectDataset) error {
+	orders, err := prepareSorts(s, opts, q)
+	if err != nil {
+		return err
+	}
 	if len(orders) > 0 {
 		*builder = *builder.Order(orders...)
 	}
+	return nil
 }
 
 func preparePagination(q *query.Query) (limit, offset int) {
@@ -588,17 +552,3 @@ func buildPagination(q *query.Query, builder *goqu.SelectDataset) {
 	*builder = *builder.Limit(uint(limit))
 	*builder = *builder.Offset(uint(offset))
 }
-
-func convertToArray(input interface{}) interface{} {
-	// Check if input is array using reflect
-	if reflect.TypeOf(input).Kind() == reflect.Array || reflect.TypeOf(input).Kind() == reflect.Slice {
-		return input
-	}
-
-	// Create an array of the input type
-	arrayType := reflect.SliceOf(reflect.TypeOf(input))
-	array := reflect.MakeSlice(arrayType, 1, 1)
-	array.Index(0).Set(reflect.ValueOf(input))
-
-	return array.Interface()
-}
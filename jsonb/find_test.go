@@ -12,6 +12,9 @@ import (
 	"github.com/rs/rest-layer/schema"
 	"github.com/rs/rest-layer/schema/query"
 	"github.com/sanity-io/litter"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/pgtypes"
 )
 
 type String struct {
@@ -88,9 +91,13 @@ func Test_buildWheres(t *testing.T) {
 			"weight": {
 				Validator: &Decimal{},
 			},
+			"q": pgsql.SearchField,
 			"address": {
 				Validator: &schema.Dict{},
 			},
+			"since": {
+				Validator: &schema.Time{},
+			},
 			"array": {
 				Validator: &schema.Array{
 					Values: schema.Field{
@@ -120,6 +127,7 @@ func Test_buildWheres(t *testing.T) {
 
 	type test struct {
 		name       string
+		opts       pgsql.Options
 		query      query.Query
 		want       []goqu.Expression
 		wantSQL    string
@@ -127,7 +135,32 @@ func Test_buildWheres(t *testing.T) {
 		wantErrStr string
 	}
 
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
 	tests := []test{
+		{
+			name: "query.GreaterThan: time",
+			query: query.Query{
+				Predicate: query.Predicate{&query.GreaterThan{Field: "since", Value: since}},
+			},
+			want: []goqu.Expression{
+				goqu.L("?->>?", goqu.C("payload"), goqu.V("since")).Gt(since),
+			},
+			wantSQL:  `SELECT * FROM "table" WHERE ("payload"->>? > ?)`,
+			wantArgs: []interface{}{"since", since},
+		},
+		{
+			name: "query.GreaterThan: time with time zones",
+			opts: pgsql.Options{TimeZone: true},
+			query: query.Query{
+				Predicate: query.Predicate{&query.GreaterThan{Field: "since", Value: since}},
+			},
+			want: []goqu.Expression{
+				goqu.Cast(goqu.L("?->>?", goqu.C("payload"), goqu.V("since")), "TIMESTAMPTZ").Gt(since),
+			},
+			wantSQL:  `SELECT * FROM "table" WHERE (CAST("payload"->>? AS TIMESTAMPTZ) > ?)`,
+			wantArgs: []interface{}{"since", since},
+		},
 		{
 			name: "query.And",
 			query: query.Query{
@@ -374,31 +407,31 @@ func Test_buildWheres(t *testing.T) {
 			},
 		},
 		{
-			name: "TODO: query.Eq: array",
+			name: "query.Eq: array",
 			query: query.Query{
 				Predicate: query.Predicate{&query.Equal{Field: "array", Value: []string{"John", "Doe"}}},
 			},
 			want: []goqu.Expression{
-				goqu.L("? $$| ?", goqu.L("?->?", goqu.C("payload"), goqu.V("array")), pq.Array([]string{"John", "Doe"})),
+				goqu.L("? @> ?::jsonb", goqu.L("?->?", goqu.C("payload"), goqu.V("array")), `["John","Doe"]`),
 			},
-			wantSQL: `SELECT * FROM "table" WHERE "payload"->? ?| ?`,
+			wantSQL: `SELECT * FROM "table" WHERE "payload"->? @> ?::jsonb`,
 			wantArgs: []interface{}{
 				"array",
-				`{"John","Doe"}`,
+				`["John","Doe"]`,
 			},
 		},
 		{
-			name: "TODO: query.Eq: array single",
+			name: "query.Eq: array single",
 			query: query.Query{
 				Predicate: query.Predicate{&query.Equal{Field: "array-with-typer", Value: 1}},
 			},
 			want: []goqu.Expression{
-				goqu.L("? $$| ?", goqu.L("?->?", goqu.C("payload"), goqu.V("array-with-typer")), pq.Array([]int{1})),
+				goqu.L("? @> ?::jsonb", goqu.L("?->?", goqu.C("payload"), goqu.V("array-with-typer")), `[1]`),
 			},
-			wantSQL: `SELECT * FROM "table" WHERE "payload"->? ?| ?`,
+			wantSQL: `SELECT * FROM "table" WHERE "payload"->? @> ?::jsonb`,
 			wantArgs: []interface{}{
 				"array-with-typer",
-				`{1}`,
+				`[1]`,
 			},
 		},
 		{
@@ -416,31 +449,74 @@ func Test_buildWheres(t *testing.T) {
 			},
 		},
 		{
-			name: "TODO: query.NotEq: array",
+			name: "query.NotEq: array",
 			query: query.Query{
 				Predicate: query.Predicate{&query.NotEqual{Field: "array", Value: []string{"John", "Doe"}}},
 			},
 			want: []goqu.Expression{
-				goqu.L("NOT (? $$| ?)", goqu.L("?->?", goqu.C("payload"), goqu.V("array")), pq.Array([]string{"John", "Doe"})),
+				goqu.L("NOT coalesce(?, false)", goqu.L("? @> ?::jsonb", goqu.L("?->?", goqu.C("payload"), goqu.V("array")), `["John","Doe"]`)),
 			},
-			wantSQL: `SELECT * FROM "table" WHERE NOT ("payload"->? ?| ?)`,
+			wantSQL: `SELECT * FROM "table" WHERE NOT coalesce("payload"->? @> ?::jsonb, false)`,
 			wantArgs: []interface{}{
 				"array",
-				`{"John","Doe"}`,
+				`["John","Doe"]`,
 			},
 		},
 		{
-			name: "TODO: query.NotEq: array single",
+			name: "query.NotEq: array single",
 			query: query.Query{
 				Predicate: query.Predicate{&query.NotEqual{Field: "array-with-typer", Value: 1}},
 			},
 			want: []goqu.Expression{
-				goqu.L("NOT (? $$| ?)", goqu.L("?->?", goqu.C("payload"), goqu.V("array-with-typer")), pq.Array([]int{1})),
+				goqu.L("NOT coalesce(?, false)", goqu.L("? @> ?::jsonb", goqu.L("?->?", goqu.C("payload"), goqu.V("array-with-typer")), `[1]`)),
+			},
+			wantSQL: `SELECT * FROM "table" WHERE NOT coalesce("payload"->? @> ?::jsonb, false)`,
+			wantArgs: []interface{}{
+				"array-with-typer",
+				`[1]`,
+			},
+		},
+		{
+			name: "query.In: array",
+			query: query.Query{
+				Predicate: query.Predicate{&query.In{Field: "array-with-typer", Values: []query.Value{1, []any{2, 3}}}},
+			},
+			want: []goqu.Expression{
+				goqu.L("? @> ANY(?::jsonb[])", goqu.L("?->?", goqu.C("payload"), goqu.V("array-with-typer")), pq.Array([]string{"[1]", "[2,3]"})),
 			},
-			wantSQL: `SELECT * FROM "table" WHERE NOT ("payload"->? ?| ?)`,
+			wantSQL: `SELECT * FROM "table" WHERE "payload"->? @> ANY(?::jsonb[])`,
 			wantArgs: []interface{}{
 				"array-with-typer",
-				`{1}`,
+				`{"[1]","[2,3]"}`,
+			},
+		},
+		{
+			name: "pgsql.ContainsAny",
+			query: query.Query{
+				Predicate: query.Predicate{&pgsql.ContainsAny{Field: "array", Values: []any{"John", "Doe"}}},
+			},
+			want: []goqu.Expression{
+				goqu.L("? @> ANY(?::jsonb[])", goqu.L("?->?", goqu.C("payload"), goqu.V("array")), pq.Array([]string{`["John"]`, `["Doe"]`})),
+			},
+			wantSQL: `SELECT * FROM "table" WHERE "payload"->? @> ANY(?::jsonb[])`,
+			wantArgs: []interface{}{
+				"array",
+				`{"[\"John\"]","[\"Doe\"]"}`,
+			},
+		},
+		{
+			name: "pgsql.Size",
+			query: query.Query{
+				Predicate: query.Predicate{&pgsql.Size{Field: "array", Op: pgsql.SizeGreaterOrEqual, Value: 2}},
+			},
+			want: []goqu.Expression{
+				goqu.L("jsonb_array_length(CASE WHEN jsonb_typeof(?) = 'array' THEN ? END) >= ?", goqu.L("?->?", goqu.C("payload"), goqu.V("array")), goqu.L("?->?", goqu.C("payload"), goqu.V("array")), 2),
+			},
+			wantSQL: `SELECT * FROM "table" WHERE jsonb_array_length(CASE WHEN jsonb_typeof("payload"->?) = 'array' THEN "payload"->? END) >= ?`,
+			wantArgs: []interface{}{
+				"array",
+				"array",
+				int64(2),
 			},
 		},
 		{
@@ -558,40 +634,37 @@ func Test_buildWheres(t *testing.T) {
 		{
 			name: "query.Regex: case insensitive",
 			query: query.Query{
-				Predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("(i)John")}},
+				Predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("(?i)John")}},
 			},
 			want: []goqu.Expression{
-				goqu.L("?->>?", goqu.C("payload"), goqu.V("name")).RegexpILike("John"),
+				goqu.L("?->>?", goqu.C("payload"), goqu.V("name")).RegexpILike("john"),
 			},
 			wantSQL: `SELECT * FROM "table" WHERE ("payload"->>? ~* ?)`,
 			wantArgs: []interface{}{
 				"name",
-				"John",
+				"john",
 			},
 		},
 		{
 			name: "query.Regex: case insensitive, negated",
 			query: query.Query{
-				Predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("(i)John"), Negated: true}},
+				Predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("(?i)John"), Negated: true}},
 			},
 			want: []goqu.Expression{
-				goqu.L("?->>?", goqu.C("payload"), goqu.V("name")).RegexpNotILike("John"),
+				goqu.L("?->>?", goqu.C("payload"), goqu.V("name")).RegexpNotILike("john"),
 			},
 			wantSQL: `SELECT * FROM "table" WHERE ("payload"->>? !~* ?)`,
 			wantArgs: []interface{}{
 				"name",
-				"John",
+				"john",
 			},
 		},
 		{
-			name: "query.Regex: unknown flag",
+			name: "query.Regex: unsupported repetition",
 			query: query.Query{
-				Predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("(t)John")}},
+				Predicate: query.Predicate{&query.Regex{Field: "name", Value: regexp.MustCompile("a{300}")}},
 			},
-			want: []goqu.Expression{
-				goqu.L("?->>?", goqu.C("payload"), goqu.V("name")).RegexpNotILike("John"),
-			},
-			wantErrStr: "unsupported regex flag t",
+			wantErrStr: "regex a{300}: repetition count exceeds the PostgreSQL limit of 255",
 		},
 		{
 			name: "query.Exist",
@@ -666,6 +739,25 @@ func Test_buildWheres(t *testing.T) {
 				int64(30),
 			},
 		},
+		{
+			name: "query.ElementMatch: search field name",
+			query: query.Query{
+				Predicate: query.Predicate{&query.ElemMatch{Field: "object-array", Exps: []query.Expression{&query.Equal{Field: "q", Value: "car"}}}},
+			},
+			want: []goqu.Expression{
+				goqu.Select("1").From(goqu.L("jsonb_array_elements(?) AS ?", goqu.L("?->>?", goqu.C("payload"), goqu.V("object-array")), goqu.C("object-array"))).Where(
+					goqu.And(
+						goqu.L("?->>?", goqu.C("object-array"), goqu.V("q")).Eq("car"),
+					),
+				),
+			},
+			wantSQL: `SELECT * FROM "table" WHERE (SELECT "1" FROM jsonb_array_elements("payload"->>?) AS "object-array" WHERE ("object-array"->>? = ?))`,
+			wantArgs: []interface{}{
+				"object-array",
+				"q",
+				"car",
+			},
+		},
 		{
 			name: "query.ElementMatch: nested",
 			query: query.Query{
@@ -686,11 +778,24 @@ func Test_buildWheres(t *testing.T) {
 				int64(30),
 			},
 		},
+		{
+			name: "query.Equal: search",
+			query: query.Query{
+				Predicate: query.Predicate{
+					&query.Equal{Field: "q", Value: "apple pie"},
+				},
+			},
+			want: []goqu.Expression{
+				goqu.L("? @@ ?", goqu.C("search_vector"), goqu.L("websearch_to_tsquery(?::regconfig, ?)", "simple", "apple pie")),
+			},
+			wantSQL:  `SELECT * FROM "table" WHERE "search_vector" @@ websearch_to_tsquery(?::regconfig, ?)`,
+			wantArgs: []interface{}{"simple", "apple pie"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			exprs, err := predicteToExpressions("", &testSchema, tt.query.Predicate)
+			exprs, err := predicteToExpressions("", &testSchema, &tt.opts, tt.query.Predicate)
 			if err != nil {
 				if err.Error() != tt.wantErrStr {
 					t.Fatalf("Error = %s, want %s", err.Error(), tt.wantErrStr)
@@ -707,7 +812,7 @@ func Test_buildWheres(t *testing.T) {
 			}
 
 			builder := goqu.From("table")
-			buildWheres(&testSchema, &tt.query, builder)
+			buildWheres(&testSchema, &tt.opts, &tt.query, builder)
 			sql, args, _ := builder.Prepared(true).ToSQL()
 			sql = strings.ReplaceAll(sql, "$$", "?")
 			if sql != tt.wantSQL {
@@ -747,6 +852,10 @@ func Test_buildSorts(t *testing.T) {
 			"weight": {
 				Validator: &Decimal{},
 			},
+			"since": {
+				Validator: &schema.Time{},
+			},
+			"q": pgsql.SearchField,
 			"address": {
 				Validator: &schema.Object{Schema: &addressSchema},
 			},
@@ -755,6 +864,7 @@ func Test_buildSorts(t *testing.T) {
 
 	type test struct {
 		name     string
+		opts     pgsql.Options
 		query    query.Query
 		want     []goqu.Expression
 		wantSQL  string
@@ -799,12 +909,37 @@ func Test_buildSorts(t *testing.T) {
 				"age",
 			},
 		},
+		{
+			name: "query.Sort: search rank",
+			query: query.Query{
+				Predicate: query.Predicate{
+					&query.Equal{Field: "q", Value: "apple pie"},
+				},
+				Sort: []query.SortField{
+					{Name: "q", Reversed: true},
+					{Name: "age", Reversed: false},
+				},
+			},
+			wantSQL:  `SELECT * FROM "table" ORDER BY ts_rank("search_vector", websearch_to_tsquery(?::regconfig, ?)) DESC, "payload"->>? ASC`,
+			wantArgs: []interface{}{"simple", "apple pie", "age"},
+		},
+		{
+			name: "query.Sort: time with time zones",
+			opts: pgsql.Options{TimeZone: true},
+			query: query.Query{
+				Sort: []query.SortField{{Name: "since", Reversed: true}},
+			},
+			wantSQL:  `SELECT * FROM "table" ORDER BY CAST("payload"->>? AS TIMESTAMPTZ) DESC`,
+			wantArgs: []interface{}{"since"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			builder := goqu.From("table")
-			buildSorts(&testSchema, &tt.query, builder)
+			if err := buildSorts(&testSchema, &tt.opts, &tt.query, builder); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 			sql, args, _ := builder.Prepared(true).ToSQL()
 			if sql != tt.wantSQL {
 				t.Errorf("SQL = %+#v, want %+#v", sql, tt.wantSQL)
@@ -848,12 +983,58 @@ func Test_buildSelects(t *testing.T) {
 					{Name: "age"},
 				},
 			},
-			wantSQL: `SELECT "id", "updated", "etag", jsonb_strip_nulls(jsonb_build_object('address', payload->?, 'age', payload->?)) AS "payload" FROM "table"`,
+			wantSQL: `SELECT "id", "updated", "etag", jsonb_strip_nulls(jsonb_build_object('address', CASE WHEN jsonb_typeof(payload->?) = 'object' THEN jsonb_build_object('city', payload->?->?) ELSE payload->? END, 'age', payload->?)) AS "payload" FROM "table"`,
 			wantArgs: []interface{}{
+				"address",
+				"address", "city",
 				"address",
 				"age",
 			},
 		},
+		{
+			name: "query.Select: nested merged",
+			query: query.Query{
+				Projection: []query.ProjectionField{
+					{Name: "address.city"},
+					{Name: "address", Children: query.Projection{{Name: "zip"}}},
+				},
+			},
+			wantSQL: `SELECT "id", "updated", "etag", jsonb_strip_nulls(jsonb_build_object('address', CASE WHEN jsonb_typeof(payload->?) = 'object' THEN jsonb_build_object('city', payload->?->?, 'zip', payload->?->?) ELSE payload->? END)) AS "payload" FROM "table"`,
+			wantArgs: []interface{}{
+				"address",
+				"address", "city",
+				"address", "zip",
+				"address",
+			},
+		},
+		{
+			name: "query.Select: nested and whole",
+			query: query.Query{
+				Projection: []query.ProjectionField{
+					{Name: "address.city"},
+					{Name: "address"},
+				},
+			},
+			wantSQL: `SELECT "id", "updated", "etag", jsonb_strip_nulls(jsonb_build_object('address', payload->?)) AS "payload" FROM "table"`,
+			wantArgs: []interface{}{
+				"address",
+			},
+		},
+		{
+			name: "query.Select: alias",
+			query: query.Query{
+				Projection: []query.ProjectionField{
+					{Name: "name", Alias: "n"},
+					{Name: "address.city", Alias: "city"},
+					{Name: "id"},
+				},
+			},
+			wantSQL: `SELECT "id", "updated", "etag", jsonb_strip_nulls(jsonb_build_object('name', payload->?, 'address.city', payload->?->?)) AS "payload" FROM "table"`,
+			wantArgs: []interface{}{
+				"name",
+				"address", "city",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -871,3 +1052,85 @@ func Test_buildSelects(t *testing.T) {
 		})
 	}
 }
+
+func Test_buildWheres_pgtypes(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"price":   {Filterable: true, Validator: pgtypes.Decimal{Precision: 10, Scale: 2}},
+			"ref":     {Filterable: true, Validator: pgtypes.UUID{}},
+			"born":    {Filterable: true, Validator: pgtypes.Date{}},
+			"network": {Filterable: true, Validator: pgtypes.CIDR{}},
+			"email":   {Filterable: true, Validator: &pgtypes.CIText{}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"decimal", `{price: {$gt: "10.5"}}`, `SELECT * FROM "table" WHERE (CAST("payload"->>? AS NUMERIC(10,2)) > ?)`, []interface{}{"price", "10.50"}},
+		{"uuid", `{ref: "A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11"}`, `SELECT * FROM "table" WHERE (CAST("payload"->>? AS UUID) = ?)`, []interface{}{"ref", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"}},
+		{"date", `{born: {$lt: "2000-01-01"}}`, `SELECT * FROM "table" WHERE (CAST("payload"->>? AS DATE) < ?)`, []interface{}{"born", "2000-01-01"}},
+		{"cidr", `{network: "10.0.0.0/8"}`, `SELECT * FROM "table" WHERE (CAST("payload"->>? AS CIDR) = ?)`, []interface{}{"network", "10.0.0.0/8"}},
+		{"citext", `{email: "John@Example.com"}`, `SELECT * FROM "table" WHERE (CAST("payload"->>? AS CITEXT) = ?)`, []interface{}{"email", "John@Example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := query.New("", tt.query, "", nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := q.Predicate.Prepare(sc); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			builder := goqu.From("table")
+			buildWheres(sc, &pgsql.Options{}, q, builder)
+			sql, args, _ := builder.Prepared(true).ToSQL()
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_buildSorts_point(t *testing.T) {
+	sc := &schema.Schema{
+		Fields: schema.Fields{
+			"home": {Filterable: true, Sortable: true, Validator: pgtypes.Point{}},
+		},
+	}
+	q := &query.Query{
+		Predicate: query.Predicate{&pgsql.Near{Field: "home", Lat: 48.8566, Lng: 2.3522, Radius: 5000}},
+		Sort:      query.Sort{{Name: "home"}},
+	}
+
+	builder := goqu.From("table")
+	if err := buildWheres(sc, &pgsql.Options{}, q, builder); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := buildSorts(sc, &pgsql.Options{}, q, builder); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sql, _, _ := builder.Prepared(true).ToSQL()
+
+	point := `point(("payload" #>> '{home,lng}')::float8, ("payload" #>> '{home,lat}')::float8)`
+	for _, want := range []string{
+		`WHERE (` + point + ` <@ box(point(?, ?), point(?, ?)) AND`,
+		`ORDER BY (2 * ? * asin(least(1, sqrt(power(sin(radians((` + point + `)[1] - ?) / 2), 2)`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL = %s, want %s", sql, want)
+		}
+	}
+
+	// Without a Near predicate, there is no distance to sort on
+	if err := buildSorts(sc, &pgsql.Options{}, &query.Query{Sort: q.Sort}, goqu.From("table")); err == nil {
+		t.Error("Expected an error sorting on a point field without a Near predicate")
+	}
+}
@@ -0,0 +1,52 @@
+package jsonb
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/schema/query"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Revisions(ctx context.Context, id any) (revisions []*pgsql.Revision, err error) {
+	if !s.opts.History {
+		return nil, pgsql.ErrHistoryDisabled
+	}
+
+	err = s.exec.Run(ctx, "Revisions", func(ctx context.Context, q internal.Querier) (err error) {
+		revisions, err = internal.ListRevisions(ctx, q, s.table, id)
+		return err
+	})
+	return revisions, err
+}
+
+func (s store) FindAsOf(ctx context.Context, q *query.Query, at time.Time) (*resource.ItemList, error) {
+	return s.Find(pgsql.NewAsOfContext(ctx, at), q)
+}
+
+func (s store) CountAsOf(ctx context.Context, q *query.Query, at time.Time) (int, error) {
+	return s.Count(pgsql.NewAsOfContext(ctx, at), q)
+}
+
+// source returns the relation reads select from: the table itself, or its
+// state rebuilt from the history table when ctx carries an as-of time.
+func (s store) source(ctx context.Context) (any, error) {
+	at, ok := pgsql.AsOfFromContext(ctx)
+	if !ok {
+		return goqu.T(s.table), nil
+	}
+	if !s.opts.History {
+		return nil, pgsql.ErrHistoryDisabled
+	}
+
+	return internal.AsOf(s.table, at,
+		goqu.C("item_id").As("id"),
+		goqu.C("new_etag").As("etag"),
+		goqu.C("new_updated").As("updated"),
+		goqu.L("? - 'id'", goqu.C("new_payload")).As("payload"),
+	).As(s.table), nil
+}
@@ -0,0 +1,46 @@
+package jsonb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+)
+
+func TestStore_source(t *testing.T) {
+	at := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := store{table: "table", dialect: goqu.Dialect("postgres"), opts: pgsql.NewOptions(pgsql.WithHistory())}
+
+	source, err := s.source(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sql, _, _ := s.dialect.From(source).ToSQL()
+	if want := `SELECT * FROM "table"`; sql != want {
+		t.Errorf("SQL = %s, want %s", sql, want)
+	}
+
+	source, err = s.source(pgsql.NewAsOfContext(context.Background(), at))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sql, args, _ := s.dialect.From(source).Prepared(true).ToSQL()
+	wantSQL := `SELECT * FROM (SELECT "item_id" AS "id", "new_etag" AS "etag", "new_updated" AS "updated", "new_payload" - 'id' AS "payload" ` +
+		`FROM (SELECT DISTINCT ON ("item_id") * FROM "table_history" WHERE ("changed_at" <= $1) ORDER BY "item_id" ASC, "revision" DESC) AS "revision" ` +
+		`WHERE ("operation" != $2)) AS "table"`
+	if sql != wantSQL {
+		t.Errorf("SQL = %s, want %s", sql, wantSQL)
+	}
+	if wantArgs := []any{at, "delete"}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Args = %#v, want %#v", args, wantArgs)
+	}
+
+	s.opts = pgsql.NewOptions()
+	if _, err := s.source(pgsql.NewAsOfContext(context.Background(), at)); err != pgsql.ErrHistoryDisabled {
+		t.Errorf("Error = %v, want %v", err, pgsql.ErrHistoryDisabled)
+	}
+}
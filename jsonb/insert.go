@@ -3,10 +3,8 @@ package jsonb
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
-	"log/slog"
-	"reflect"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/rs/rest-layer/resource"
@@ -17,17 +15,23 @@ import (
 )
 
 func (s store) Insert(ctx context.Context, items []*resource.Item) (err error) {
-	for _, item := range items {
-		if err = s.insertOne(ctx, item); err != nil {
-			return
-		}
-	}
+	defer internal.Measure(s.opts, s.table, "Insert", time.Now(), &err)
 
-	return
+	return s.exec.RunWrite(ctx, "Insert", func(ctx context.Context, q internal.Querier) error {
+		for _, item := range items {
+			if err := s.insertOne(ctx, q, item); err != nil {
+				return err
+			}
+			if err := internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationInsert, nil, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func prepareInsertQuery(dialect goqu.DialectWrapper, s *schema.Schema, table string, item *resource.Item) (string, []any, error) {
-	row := internal.CopyRow(item.Payload)
+func prepareInsertQuery(dialect goqu.DialectWrapper, s *schema.Schema, opts *pgsql.Options, table string, item *resource.Item) (string, []any, error) {
+	row := internal.CopyRow(internal.UTCPayload(opts, item.Payload))
 	delete(row, "id")
 
 	buf := bytes.Buffer{}
@@ -37,42 +41,36 @@ func prepareInsertQuery(dialect goqu.DialectWrapper, s *schema.Schema, table str
 
 	tableRow := map[string]any{}
 	tableRow["etag"] = item.ETag
-	tableRow["id"] = item.ID
 	tableRow["updated"] = item.Updated
 	tableRow["payload"] = buf.String()
 
 	builder := dialect.Insert(table)
-	useSerial := reflect.DeepEqual(s.Fields["id"], pgsql.SerialID)
-	if useSerial {
+	if internal.IDs(s, opts).Generated() {
 		builder = builder.Returning(goqu.L("id"))
+	} else {
+		tableRow["id"] = item.ID
 	}
 	return builder.Prepared(true).Rows(tableRow).ToSQL()
 }
 
-func (s store) insertOne(ctx context.Context, item *resource.Item) error {
-	sqlStr, args, err := prepareInsertQuery(s.dialect, s.schema, s.table, item)
+func (s store) insertOne(ctx context.Context, q internal.Querier, item *resource.Item) error {
+	sqlStr, args, err := prepareInsertQuery(s.dialect, s.schema, s.opts, s.table, item)
 	if err != nil {
 		return err
 	}
 
-	slog.DebugContext(ctx, "pgsql.Insert", "sql", sqlStr, "args", args)
-
-	tx := pgsql.TransactionFromContext(ctx)
-	var result *sql.Row
-	if tx != nil {
-		result = tx.QueryRowContext(ctx, sqlStr, args...)
-	} else {
-		result = s.db.QueryRowContext(ctx, sqlStr, args...)
-	}
-
+	result := q.QueryRowContext(ctx, sqlStr, args...)
 	if result.Err() != nil {
 		return result.Err()
 	}
 
-	useSerial := reflect.DeepEqual(s.schema.Fields["id"], pgsql.SerialID)
-	if useSerial {
-		var id string
-		if err := result.Scan(&id); err != nil {
+	if ids := internal.IDs(s.schema, s.opts); ids.Generated() {
+		var value any
+		if err := result.Scan(&value); err != nil {
+			return err
+		}
+		id, err := ids.ParseID(value)
+		if err != nil {
 			return err
 		}
 		item.Payload["id"] = id
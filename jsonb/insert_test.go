@@ -41,7 +41,7 @@ func TestStore_prepareInsertQuery(t *testing.T) {
 			ETag: "123",
 		}
 
-		sqlStr, args, err := prepareInsertQuery(goqu.Dialect("postgres"), schema, "table", item)
+		sqlStr, args, err := prepareInsertQuery(goqu.Dialect("postgres"), schema, &pgsql.Options{}, "table", item)
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -59,4 +59,61 @@ func TestStore_prepareInsertQuery(t *testing.T) {
 			t.Errorf("Expected args: %v, got: %v", expectedArgs, args)
 		}
 	})
+
+	t.Run("insert: times in UTC", func(t *testing.T) {
+		schema := &schema.Schema{
+			Fields: schema.Fields{
+				"id":    pgsql.IDField,
+				"since": {Validator: &schema.Time{}},
+			},
+		}
+		since := time.Date(2020, 1, 1, 2, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+		item := &resource.Item{
+			ID:      "1234567890abcdefjhij",
+			Payload: map[string]interface{}{"since": since},
+			ETag:    "123",
+		}
+
+		_, args, err := prepareInsertQuery(goqu.Dialect("postgres"), schema, &pgsql.Options{TimeZone: true}, "table", item)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expectedPayload := `{"since":"2020-01-01T00:00:00Z"}
+`
+		if args[2] != expectedPayload {
+			t.Errorf("Expected payload: %s, got: %v", expectedPayload, args[2])
+		}
+		if item.Payload["since"] != since {
+			t.Errorf("Item payload modified: %v", item.Payload["since"])
+		}
+	})
+	t.Run("insert: generated ids", func(t *testing.T) {
+		for _, ids := range []pgsql.IDStrategy{pgsql.Serial{}, pgsql.Identity{}} {
+			schema := &schema.Schema{
+				Fields: schema.Fields{
+					"id":   pgsql.NewIDField(ids),
+					"name": {Validator: &schema.String{}},
+				},
+			}
+			item := &resource.Item{
+				ID:      "0",
+				Payload: map[string]interface{}{"name": "John"},
+				ETag:    "123",
+			}
+
+			sqlStr, args, err := prepareInsertQuery(goqu.Dialect("postgres"), schema, &pgsql.Options{}, "table", item)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expectedQuery := `INSERT INTO "table" ("etag", "payload", "updated") VALUES ($1, $2, $3) RETURNING id`
+			if sqlStr != expectedQuery {
+				t.Errorf("%T: expected query: %s, got: %s", ids, expectedQuery, sqlStr)
+			}
+			if len(args) != 3 {
+				t.Errorf("%T: expected 3 args, got: %v", ids, args)
+			}
+		}
+	})
 }
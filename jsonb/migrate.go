@@ -2,40 +2,78 @@ package jsonb
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"reflect"
 	"strings"
 
 	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 	"github.com/rs/rest-layer/schema"
 )
 
 func (s store) Migrate(ctx context.Context, sc *schema.Schema) (err error) {
-	sqlQuery, sqlParams, err := buildCreateQuery(s.table, sc)
+	db, err := s.exec.Resolve(ctx)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.db.ExecContext(ctx, sqlQuery, sqlParams...)
-	return err
+	return s.migrate(ctx, db, sc)
 }
 
-func buildCreateQuery(tableName string, s *schema.Schema) (sqlQuery string, sqlParams []any, err error) {
-	schemaQuery := buildCreateTable(s)
+func (s store) migrate(ctx context.Context, db *sql.DB, sc *schema.Schema) (err error) {
+	q := s.exec.Trace(db, "Migrate")
+	sqlQuery, sqlParams, err := buildCreateQuery(s.table, sc, s.opts)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range internal.ExtensionQueries(sc) {
+		if _, err = q.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	_, err = q.ExecContext(ctx, sqlQuery, sqlParams...)
+	if err != nil {
+		return err
+	}
+
+	rlsQueries, err := internal.RowSecurityQueries(s.table, s.opts.RowSecurity)
+	if err != nil {
+		return err
+	}
+	queries := append(internal.TimeZoneQueries(s.table, s.opts, "updated"), internal.SoftDeleteQueries(s.table, s.opts)...)
+	queries = append(queries, internal.HistoryQueries(s.table, s.opts)...)
+	queries = append(queries, internal.SearchQueries(s.table, sc, func(path string) string {
+		return internal.JSONText("payload", path)
+	})...)
+	queries = append(queries, internal.PointIndexQueries(s.table, sc, func(path string) string {
+		return internal.JSONPoint("payload", path)
+	})...)
+	queries = append(queries, internal.NotifyTriggerQueries(s.table, "etag", s.opts)...)
+	queries = append(queries, internal.OutboxQueries(s.opts)...)
+	queries = append(queries, rlsQueries...)
+	for _, query := range queries {
+		if _, err = q.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func buildCreateQuery(tableName string, s *schema.Schema, opts *pgsql.Options) (sqlQuery string, sqlParams []any, err error) {
+	schemaQuery := buildCreateTable(s, opts)
 	sqlQuery = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (%s,PRIMARY KEY(id))`, tableName, schemaQuery)
 	return sqlQuery, sqlParams, nil
 }
 
-func buildCreateTable(s *schema.Schema) string {
+func buildCreateTable(s *schema.Schema, opts *pgsql.Options) string {
 	fieldStrings := make([]string, 0, len(s.Fields))
 
-	if field, ok := s.Fields["id"]; ok && reflect.DeepEqual(field, pgsql.SerialID) {
-		fieldStrings = append(fieldStrings, "id SERIAL")
-	} else {
-		fieldStrings = append(fieldStrings, "id VARCHAR(24)")
-	}
+	fieldStrings = append(fieldStrings, "id "+internal.IDs(s, opts).ColumnType())
 	fieldStrings = append(fieldStrings, "etag VARCHAR(32)")
-	fieldStrings = append(fieldStrings, "updated TIMESTAMP")
+	fieldStrings = append(fieldStrings, "updated "+internal.TimeType(opts))
 	fieldStrings = append(fieldStrings, "payload JSONB")
 
 	return strings.Join(fieldStrings, ",")
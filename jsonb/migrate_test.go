@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/rs/rest-layer/schema"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
 )
 
 func TestStore_buildCreateQuery(t *testing.T) {
@@ -22,7 +24,7 @@ func TestStore_buildCreateQuery(t *testing.T) {
 		},
 	}
 
-	query, params, err := buildCreateQuery("table", schema)
+	query, params, err := buildCreateQuery("table", schema, &pgsql.Options{})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -36,4 +38,35 @@ func TestStore_buildCreateQuery(t *testing.T) {
 	if !reflect.DeepEqual(params, expectedParams) {
 		t.Errorf("Expected params: %#v, got: %#v", expectedParams, params)
 	}
+
+	query, _, err = buildCreateQuery("table", schema, &pgsql.Options{TimeZone: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expectedQuery = `CREATE TABLE IF NOT EXISTS "table" (id VARCHAR(24),etag VARCHAR(32),updated TIMESTAMPTZ,payload JSONB,PRIMARY KEY(id))`
+	if query != expectedQuery {
+		t.Errorf("Expected query: %s, got: %s", expectedQuery, query)
+	}
+
+	// The id field may be tweaked, the strategy is its validator
+	idField := pgsql.SerialID
+	idField.Description = "The order number"
+	schema.Fields["id"] = idField
+	query, _, err = buildCreateQuery("table", schema, &pgsql.Options{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expectedQuery = `CREATE TABLE IF NOT EXISTS "table" (id SERIAL,etag VARCHAR(32),updated TIMESTAMP,payload JSONB,PRIMARY KEY(id))`
+	if query != expectedQuery {
+		t.Errorf("Expected query: %s, got: %s", expectedQuery, query)
+	}
+
+	query, _, err = buildCreateQuery("table", schema, &pgsql.Options{IDStrategy: pgsql.UUIDv7{}})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expectedQuery = `CREATE TABLE IF NOT EXISTS "table" (id UUID,etag VARCHAR(32),updated TIMESTAMP,payload JSONB,PRIMARY KEY(id))`
+	if query != expectedQuery {
+		t.Errorf("Expected query: %s, got: %s", expectedQuery, query)
+	}
 }
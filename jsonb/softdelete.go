@@ -0,0 +1,75 @@
+package jsonb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/rs/rest-layer/resource"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
+)
+
+func (s store) Restore(ctx context.Context, item *resource.Item) error {
+	if !s.opts.SoftDelete {
+		return pgsql.ErrSoftDeleteDisabled
+	}
+
+	sqlStr, args, err := s.dialect.Update(s.table).
+		Set(goqu.Record{pgsql.DeletedColumn: nil}).
+		Where(prepareDelete(item), goqu.C(pgsql.DeletedColumn).IsNotNull()).
+		Prepared(true).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	return s.exec.RunWrite(ctx, "Restore", func(ctx context.Context, q internal.Querier) error {
+		affect, err := q.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := affect.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return internal.Conflict(ctx, q, s.table, "etag", item.ID, goqu.C(pgsql.DeletedColumn).IsNotNull())
+		}
+
+		// The item reappears, as if inserted again
+		return internal.RecordChange(ctx, q, s.table, s.opts, pgsql.OperationInsert, nil, item)
+	})
+}
+
+func (s store) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	if !s.opts.SoftDelete {
+		return 0, pgsql.ErrSoftDeleteDisabled
+	}
+
+	sqlStr, args, err := s.dialect.Delete(s.table).
+		Where(internal.PurgeExpression(retention.Seconds())).
+		Prepared(true).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var res sql.Result
+	err = s.exec.Run(ctx, "Purge", func(ctx context.Context, q internal.Querier) (err error) {
+		res, err = q.ExecContext(ctx, sqlStr, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(cnt), nil
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/rs/rest-layer/schema"
 
 	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+
+	pgsql "github.com/Dragomir-Ivanov/rest-layer-postgres"
+	"github.com/Dragomir-Ivanov/rest-layer-postgres/internal"
 )
 
 type PostgresStorer interface {
@@ -18,17 +21,39 @@ type PostgresStorer interface {
 
 type store struct {
 	table   string
-	db      *sql.DB
 	dialect goqu.DialectWrapper
 	schema  *schema.Schema
+	opts    *pgsql.Options
+	exec    *internal.Executor
 }
 
-func NewStore(table string, db *sql.DB, sc *schema.Schema) PostgresStorer {
+func NewStore(table string, db *sql.DB, sc *schema.Schema, opts ...pgsql.Option) PostgresStorer {
+	o := pgsql.NewOptions(opts...)
 	s := &store{
 		table:   table,
-		db:      db,
 		dialect: goqu.Dialect("postgres"),
 		schema:  sc,
+		opts:    o,
+		exec:    internal.NewExecutor(table, db, o),
+	}
+
+	return s
+}
+
+// NewTenantStore returns a store running its statements on the database
+// returned by resolve for each request, e.g. a pgsql.TenantPools. Every
+// database is migrated on first use, so AutoMigrate is not needed.
+func NewTenantStore(table string, resolve pgsql.DBResolver, sc *schema.Schema, opts ...pgsql.Option) PostgresStorer {
+	o := pgsql.NewOptions(opts...)
+	s := &store{
+		table:   table,
+		dialect: goqu.Dialect("postgres"),
+		schema:  sc,
+		opts:    o,
+		exec:    &internal.Executor{Table: table, Resolve: resolve, Options: o},
+	}
+	s.exec.Migrate = func(ctx context.Context, db *sql.DB) error {
+		return s.migrate(ctx, db, s.schema)
 	}
 
 	return s
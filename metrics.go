@@ -0,0 +1,72 @@
+package pgsql
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/rest"
+)
+
+// The outcomes of the store operations reported to Metrics.
+const (
+	OutcomeOK                 = "ok"
+	OutcomePreconditionFailed = "precondition_failed"
+	OutcomeConflict           = "conflict"
+	OutcomeNotFound           = "not_found"
+	OutcomeError              = "error"
+)
+
+// Metrics records the operations of the stores: Find, Reduce, Count, Insert,
+// Update, Delete and Clear. PrometheusMetrics implements it.
+type Metrics interface {
+	// ObserveOperation records an operation of the store of table, with its
+	// outcome, one of the Outcome* values, and its duration.
+	ObserveOperation(table, operation, outcome string, duration time.Duration)
+}
+
+// WithMetrics makes the store record its operations in m.
+func WithMetrics(m Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+// Outcome returns the outcome of an operation that returned err: etag
+// mismatches are precondition failures, resource.ErrConflict as well as
+// unique or exclusion constraint violations, e.g. an Insert of an existing
+// id, are conflicts, and writes to missing items, reported by rest-layer as
+// 404s, are not found. Any other error is an error.
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeOK
+	}
+
+	var restErr *rest.Error
+	if errors.As(err, &restErr) {
+		switch restErr.Code {
+		case http.StatusPreconditionFailed:
+			return OutcomePreconditionFailed
+		case http.StatusConflict:
+			return OutcomeConflict
+		case http.StatusNotFound:
+			return OutcomeNotFound
+		}
+	}
+	if errors.Is(err, resource.ErrNotFound) {
+		return OutcomeNotFound
+	}
+	if errors.Is(err, resource.ErrConflict) {
+		return OutcomeConflict
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505", "23P01":
+			return OutcomeConflict
+		}
+	}
+	return OutcomeError
+}
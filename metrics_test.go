@@ -0,0 +1,79 @@
+package pgsql
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/rest-layer/resource"
+	"github.com/rs/rest-layer/rest"
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, OutcomeOK},
+		{NewPreconditionFailed("abc"), OutcomePreconditionFailed},
+		{rest.ErrPreconditionFailed, OutcomePreconditionFailed},
+		{fmt.Errorf("update: %w", NewPreconditionFailed("abc")), OutcomePreconditionFailed},
+		{resource.ErrConflict, OutcomeConflict},
+		{rest.ErrConflict, OutcomeConflict},
+		{&pq.Error{Code: "23505"}, OutcomeConflict},
+		{&pq.Error{Code: "23P01"}, OutcomeConflict},
+		{&pq.Error{Code: "42P01"}, OutcomeError},
+		{resource.ErrNotFound, OutcomeNotFound},
+		{rest.ErrNotFound, OutcomeNotFound},
+		{errors.New("failed"), OutcomeError},
+	}
+	for _, tt := range tests {
+		if got := Outcome(tt.err); got != tt.want {
+			t.Errorf("Outcome(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics(0.1, 0.01)
+	m.ObserveOperation("users", "Insert", OutcomeConflict, 50*time.Millisecond)
+	m.ObserveOperation("users", "Find", OutcomeOK, 4*time.Millisecond)
+	m.ObserveOperation("users", "Find", OutcomeOK, 250*time.Millisecond)
+	m.ObserveOperation(`a"b`, "Count", OutcomeOK, time.Millisecond)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	want := `# HELP pgsql_operations_total Operations of the stores.
+# TYPE pgsql_operations_total counter
+pgsql_operations_total{table="a\"b",operation="Count",outcome="ok"} 1
+pgsql_operations_total{table="users",operation="Find",outcome="ok"} 2
+pgsql_operations_total{table="users",operation="Insert",outcome="conflict"} 1
+# HELP pgsql_operation_duration_seconds Duration of the operations of the stores.
+# TYPE pgsql_operation_duration_seconds histogram
+pgsql_operation_duration_seconds_bucket{table="a\"b",operation="Count",outcome="ok",le="0.01"} 1
+pgsql_operation_duration_seconds_bucket{table="a\"b",operation="Count",outcome="ok",le="0.1"} 1
+pgsql_operation_duration_seconds_bucket{table="a\"b",operation="Count",outcome="ok",le="+Inf"} 1
+pgsql_operation_duration_seconds_sum{table="a\"b",operation="Count",outcome="ok"} 0.001
+pgsql_operation_duration_seconds_count{table="a\"b",operation="Count",outcome="ok"} 1
+pgsql_operation_duration_seconds_bucket{table="users",operation="Find",outcome="ok",le="0.01"} 1
+pgsql_operation_duration_seconds_bucket{table="users",operation="Find",outcome="ok",le="0.1"} 1
+pgsql_operation_duration_seconds_bucket{table="users",operation="Find",outcome="ok",le="+Inf"} 2
+pgsql_operation_duration_seconds_sum{table="users",operation="Find",outcome="ok"} 0.254
+pgsql_operation_duration_seconds_count{table="users",operation="Find",outcome="ok"} 2
+pgsql_operation_duration_seconds_bucket{table="users",operation="Insert",outcome="conflict",le="0.01"} 0
+pgsql_operation_duration_seconds_bucket{table="users",operation="Insert",outcome="conflict",le="0.1"} 1
+pgsql_operation_duration_seconds_bucket{table="users",operation="Insert",outcome="conflict",le="+Inf"} 1
+pgsql_operation_duration_seconds_sum{table="users",operation="Insert",outcome="conflict"} 0.05
+pgsql_operation_duration_seconds_count{table="users",operation="Insert",outcome="conflict"} 1
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("metrics =\n%s\nwant\n%s", got, want)
+	}
+}
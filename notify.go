@@ -0,0 +1,117 @@
+package pgsql
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OperationResync is delivered by a Subscriber after its connection was
+// re-established: changes may have been missed meanwhile.
+const OperationResync = "resync"
+
+// Change is the payload of the notifications published for every inserted,
+// updated or deleted item.
+type Change struct {
+	Table     string `json:"table"`
+	ID        string `json:"id,omitempty"`
+	ETag      string `json:"etag,omitempty"`
+	Operation string `json:"operation"`
+}
+
+// WithNotify makes the store publish a Change on channel with NOTIFY for
+// every item it inserts, updates or deletes. Notifications are sent in the
+// transaction of the change, so they are only delivered once it commits.
+func WithNotify(channel string) Option {
+	return func(o *Options) {
+		o.NotifyChannel = channel
+		o.NotifyTriggers = false
+	}
+}
+
+// WithNotifyTriggers is like WithNotify, but notifications are published by
+// triggers created by Migrate, so that changes made out of the store are
+// captured as well.
+func WithNotifyTriggers(channel string) Option {
+	return func(o *Options) {
+		o.NotifyChannel = channel
+		o.NotifyTriggers = true
+	}
+}
+
+// Subscriber listens to a notification channel on a dedicated connection,
+// reconnecting when needed, and delivers the published changes.
+type Subscriber struct {
+	listener *pq.Listener
+	changes  chan Change
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewSubscriber starts listening to channel on a new connection opened with
+// connStr.
+func NewSubscriber(connStr, channel string) (*Subscriber, error) {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, nil)
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Subscriber{
+		listener: listener,
+		changes:  make(chan Change),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+
+	return s, nil
+}
+
+// Changes returns the channel the changes are delivered on. It is closed by
+// Close.
+func (s *Subscriber) Changes() <-chan Change {
+	return s.changes
+}
+
+// Close stops listening and closes the connection.
+func (s *Subscriber) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.listener.Close()
+	})
+	return err
+}
+
+func (s *Subscriber) run() {
+	defer close(s.changes)
+
+	for {
+		var change Change
+		select {
+		case <-s.done:
+			return
+		case n, ok := <-s.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification is sent after a reconnection
+			if n == nil {
+				change = Change{Operation: OperationResync}
+			} else if err := json.Unmarshal([]byte(n.Extra), &change); err != nil {
+				continue
+			}
+		case <-time.After(90 * time.Second):
+			go s.listener.Ping()
+			continue
+		}
+
+		select {
+		case s.changes <- change:
+		case <-s.done:
+			return
+		}
+	}
+}
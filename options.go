@@ -0,0 +1,73 @@
+package pgsql
+
+// Options holds the optional behaviours shared by the classic and jsonb
+// stores. Use the With* functions to populate it through NewStore.
+type Options struct {
+	// SessionSettings, when set, makes every statement run inside a
+	// transaction that starts with the returned SET LOCAL values.
+	SessionSettings SessionSettingsFunc
+	// RowSecurity, when set, makes Migrate enable row level security on the
+	// table and (re)create the described policies.
+	RowSecurity *RowSecurity
+	// SoftDelete marks deleted rows with a deletion time instead of removing
+	// them.
+	SoftDelete bool
+	// History records every change in the <table>_history table.
+	History bool
+	// NotifyChannel, when set, is the channel changes are published on.
+	NotifyChannel string
+	// NotifyTriggers publishes changes from triggers instead of the store.
+	NotifyTriggers bool
+	// OutboxTable, when set, is the table events are written to.
+	OutboxTable string
+	// EmbeddedReferences are the referenced resources embeddable by Find,
+	// by path.
+	EmbeddedReferences map[string]EmbeddedReference
+	// NativeArrays stores the arrays of scalars in native array columns in
+	// the classic layout.
+	NativeArrays bool
+	// TimeZone stores times in TIMESTAMPTZ columns and in UTC in JSONB
+	// payloads.
+	TimeZone bool
+	// IDStrategy, when set, is the strategy of the ids of the store.
+	IDStrategy IDStrategy
+	// QueryHooks are notified of every statement the store runs.
+	QueryHooks []QueryHook
+	// Metrics, when set, records the operations of the store.
+	Metrics Metrics
+	// SlowQueries, when set, reports the slow statements of the store.
+	SlowQueries *SlowQueries
+}
+
+// Publishes tells if the store itself publishes change notifications.
+func (o *Options) Publishes() bool {
+	return o.NotifyChannel != "" && !o.NotifyTriggers
+}
+
+// Option configures a store.
+type Option func(*Options)
+
+// NewOptions applies opts on top of the default options.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSessionSettings makes the store apply the session settings returned by
+// fn at the start of the transaction of every statement it runs.
+func WithSessionSettings(fn SessionSettingsFunc) Option {
+	return func(o *Options) {
+		o.SessionSettings = fn
+	}
+}
+
+// WithRowSecurity makes Migrate enable row level security and create the
+// given policies on the store table.
+func WithRowSecurity(rs RowSecurity) Option {
+	return func(o *Options) {
+		o.RowSecurity = &rs
+	}
+}
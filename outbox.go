@@ -0,0 +1,168 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultOutboxTable is the outbox table used when none is given to
+// WithOutbox.
+const DefaultOutboxTable = "outbox"
+
+// WithOutbox makes every successful Insert, Update, Delete and Clear write an
+// event in the outbox table, in the same transaction as the change. The
+// table, shared by all the stores using it, is created by Migrate. Use a
+// Relay to publish the events.
+func WithOutbox(table string) Option {
+	return func(o *Options) {
+		if table == "" {
+			table = DefaultOutboxTable
+		}
+		o.OutboxTable = table
+	}
+}
+
+// Event is a row of the outbox table. Payload holds the new version of the
+// item, or the deleted one for deletes, and is null for items removed by
+// Clear.
+type Event struct {
+	ID        int64
+	Table     string
+	ItemID    string
+	Operation string
+	ETag      string
+	Actor     string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+	Attempts  int
+}
+
+// Relay publishes the events of an outbox table. A relay publishes the events
+// in order: a failing event stops the batch and is retried by a later one.
+// Events are claimed with FOR UPDATE SKIP LOCKED, so several relays can run
+// concurrently, but then the order across relays is only best effort.
+type Relay struct {
+	DB *sql.DB
+	// Table is the outbox table, DefaultOutboxTable if empty.
+	Table string
+	// Publish hands an event to the message bus.
+	Publish func(ctx context.Context, event Event) error
+	// BatchSize is the maximum number of events claimed at once, 100 if
+	// zero.
+	BatchSize int
+	// Interval is the delay between two polls of Run when the outbox is
+	// empty, one second if zero.
+	Interval time.Duration
+}
+
+// Run relays events until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "pgsql.Relay", "error", err)
+		} else if n > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RelayOnce claims a batch of undelivered events, publishes them and marks
+// them delivered. It returns the number of delivered events.
+func (r *Relay) RelayOnce(ctx context.Context) (delivered int, err error) {
+	table := r.Table
+	if table == "" {
+		table = DefaultOutboxTable
+	}
+	table = pq.QuoteIdentifier(table)
+	batchSize := r.BatchSize
+	if batchSize == 0 {
+		batchSize = 100
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	events, err := claimEvents(ctx, tx, table, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var publishErr error
+	for _, event := range events {
+		if publishErr = r.Publish(ctx, event); publishErr != nil {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = $1 WHERE id = $2`, table),
+				publishErr.Error(), event.ID)
+			if err != nil {
+				return 0, err
+			}
+			break
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET delivered_at = now() WHERE id = $1`, table), event.ID)
+		if err != nil {
+			return 0, err
+		}
+		delivered++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return delivered, publishErr
+}
+
+func claimEvents(ctx context.Context, tx *sql.Tx, table string, limit int) ([]Event, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, aggregate, item_id, operation, etag, actor, payload, created_at, attempts FROM %s `+
+			`WHERE delivered_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, table), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var etag, actor sql.NullString
+		var payload []byte
+		err := rows.Scan(&event.ID, &event.Table, &event.ItemID, &event.Operation, &etag, &actor, &payload, &event.CreatedAt, &event.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		event.ETag = etag.String
+		event.Actor = actor.String
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,113 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// outboxConn is a driver connection serving the pending events of an outbox
+// table and recording the statements updating them.
+type outboxConn struct {
+	events    [][]driver.Value
+	execs     []outboxExec
+	committed bool
+}
+
+type outboxExec struct {
+	query string
+	args  []any
+}
+
+func (c *outboxConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *outboxConn) Driver() driver.Driver                        { return nil }
+func (c *outboxConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *outboxConn) Close() error              { return nil }
+func (c *outboxConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *outboxConn) Commit() error             { c.committed = true; return nil }
+func (c *outboxConn) Rollback() error           { return nil }
+
+func (c *outboxConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &outboxRows{events: c.events}, nil
+}
+
+func (c *outboxConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	exec := outboxExec{query: query}
+	for _, arg := range args {
+		exec.args = append(exec.args, arg.Value)
+	}
+	c.execs = append(c.execs, exec)
+	return driver.RowsAffected(1), nil
+}
+
+type outboxRows struct {
+	events [][]driver.Value
+}
+
+func (r *outboxRows) Columns() []string {
+	return []string{"id", "aggregate", "item_id", "operation", "etag", "actor", "payload", "created_at", "attempts"}
+}
+func (r *outboxRows) Close() error { return nil }
+func (r *outboxRows) Next(dest []driver.Value) error {
+	if len(r.events) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.events[0])
+	r.events = r.events[1:]
+	return nil
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	conn := &outboxConn{events: [][]driver.Value{
+		{int64(1), "users", "a", OperationInsert, "e1", "alice", []byte(`{"id":"a"}`), created, int64(0)},
+		{int64(2), "users", "b", OperationUpdate, "e2", nil, []byte(`{"id":"b"}`), created, int64(2)},
+		{int64(3), "users", "c", OperationDelete, nil, nil, nil, created, int64(0)},
+	}}
+	db := sql.OpenDB(conn)
+	defer db.Close()
+
+	var published []Event
+	publishErr := errors.New("bus unavailable")
+	r := &Relay{DB: db, Table: "events", Publish: func(ctx context.Context, event Event) error {
+		published = append(published, event)
+		if event.ID == 2 {
+			return publishErr
+		}
+		return nil
+	}}
+
+	delivered, err := r.RelayOnce(context.Background())
+	if err != publishErr {
+		t.Errorf("Error = %v, want %v", err, publishErr)
+	}
+	if delivered != 1 {
+		t.Errorf("Delivered = %d, want 1", delivered)
+	}
+	if len(published) != 2 {
+		t.Fatalf("Published %d events, want the batch to stop at the failing one", len(published))
+	}
+	want := Event{ID: 2, Table: "users", ItemID: "b", Operation: OperationUpdate, ETag: "e2",
+		Payload: []byte(`{"id":"b"}`), CreatedAt: created, Attempts: 2}
+	if !reflect.DeepEqual(published[1], want) {
+		t.Errorf("Event = %+v, want %+v", published[1], want)
+	}
+
+	wantExecs := []outboxExec{
+		{query: `UPDATE "events" SET delivered_at = now() WHERE id = $1`, args: []any{int64(1)}},
+		{query: `UPDATE "events" SET attempts = attempts + 1, last_error = $1 WHERE id = $2`, args: []any{"bus unavailable", int64(2)}},
+	}
+	if !reflect.DeepEqual(conn.execs, wantExecs) {
+		t.Errorf("Statements = %+v, want %+v", conn.execs, wantExecs)
+	}
+	if !conn.committed {
+		t.Error("The delivery and the failed attempt were not committed")
+	}
+}
@@ -3,3 +3,9 @@ package pgsql
 type PostgresTyper interface {
 	PostgresType() string
 }
+
+// PostgresExtender is implemented by validators whose type is provided by an
+// extension, e.g. citext. Migrate creates the extension before the table.
+type PostgresExtender interface {
+	PostgresExtension() string
+}
@@ -0,0 +1,91 @@
+package pgtypes
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// Bytea validates binary data, stored as BYTEA. Values are Bytes, and base64
+// strings are accepted as input. Serialize returns base64 strings.
+type Bytea struct {
+	// MaxLen is the maximum length of the data, unbounded when 0.
+	MaxLen int
+}
+
+// Bytes is the value of a Bytea field. It is encoded in JSONB payloads in the
+// hex format of BYTEA texts, so that casts of the payloads read it back.
+type Bytes []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(`\x` + hex.EncodeToString(b))
+}
+
+// Value implements driver.Valuer.
+func (b Bytes) Value() (driver.Value, error) {
+	return []byte(b), nil
+}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Bytea) PostgresType() string {
+	return "BYTEA"
+}
+
+// Validate implements schema.FieldValidator.
+func (v Bytea) Validate(value interface{}) (interface{}, error) {
+	var b Bytes
+	switch t := value.(type) {
+	case Bytes:
+		b = t
+	case []byte:
+		b = t
+	case string:
+		decoded, err := base64.StdEncoding.DecodeString(t)
+		if err != nil {
+			return nil, errors.New("not base64 data")
+		}
+		b = decoded
+	default:
+		return nil, errors.New("not binary data")
+	}
+	if v.MaxLen > 0 && len(b) > v.MaxLen {
+		return nil, errors.New("too long")
+	}
+	return b, nil
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Bytea) Serialize(value interface{}) (interface{}, error) {
+	switch t := value.(type) {
+	case Bytes:
+		return base64.StdEncoding.EncodeToString(t), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(t), nil
+	case string:
+		// Read from a JSONB payload, or already serialized
+		if text, ok := strings.CutPrefix(t, `\x`); ok {
+			b, err := hex.DecodeString(text)
+			if err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+	}
+	return value, nil
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v Bytea) LessFunc() schema.LessFunc {
+	return func(value, other interface{}) bool {
+		b1, ok1 := value.(Bytes)
+		b2, ok2 := other.(Bytes)
+		return ok1 && ok2 && bytes.Compare(b1, b2) < 0
+	}
+}
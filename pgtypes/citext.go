@@ -0,0 +1,40 @@
+package pgtypes
+
+import (
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// CIText validates strings compared without regard to case, stored as
+// CITEXT. Migrate creates the citext extension.
+type CIText struct {
+	schema.String
+}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v CIText) PostgresType() string {
+	return "CITEXT"
+}
+
+// PostgresExtension implements pgsql.PostgresExtender.
+func (v CIText) PostgresExtension() string {
+	return "citext"
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v CIText) Serialize(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v CIText) LessFunc() schema.LessFunc {
+	return func(value, other interface{}) bool {
+		s1, ok1 := value.(string)
+		s2, ok2 := other.(string)
+		return ok1 && ok2 && strings.ToLower(s1) < strings.ToLower(s2)
+	}
+}
@@ -0,0 +1,49 @@
+package pgtypes
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// DateLayout is the layout of the values of Date.
+const DateLayout = "2006-01-02"
+
+// Date validates calendar dates, stored as DATE. Values are strings in
+// DateLayout, and times or RFC 3339 times are accepted as input, in their
+// own time zone.
+type Date struct{}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Date) PostgresType() string {
+	return "DATE"
+}
+
+// Validate implements schema.FieldValidator.
+func (v Date) Validate(value interface{}) (interface{}, error) {
+	switch t := value.(type) {
+	case time.Time:
+		return t.Format(DateLayout), nil
+	case string:
+		for _, layout := range []string{DateLayout, time.RFC3339Nano} {
+			if d, err := time.Parse(layout, t); err == nil {
+				return d.Format(DateLayout), nil
+			}
+		}
+	}
+	return nil, errors.New("not a date")
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Date) Serialize(value interface{}) (interface{}, error) {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(DateLayout), nil
+	}
+	return value, nil
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v Date) LessFunc() schema.LessFunc {
+	return lessString
+}
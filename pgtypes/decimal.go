@@ -0,0 +1,221 @@
+package pgtypes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+// Decimal validates exact decimal numbers, stored as NUMERIC. Values are
+// strings, e.g. "12.50", and numbers are accepted as input.
+type Decimal struct {
+	// Precision is the maximum number of digits, unbounded when 0.
+	Precision int
+	// Scale is the number of digits of the fractional part when Precision
+	// is set. Values are rounded to it, as PostgreSQL does.
+	Scale int
+}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Decimal) PostgresType() string {
+	if v.Precision <= 0 {
+		return "NUMERIC"
+	}
+	return fmt.Sprintf("NUMERIC(%d,%d)", v.Precision, v.Scale)
+}
+
+// Validate implements schema.FieldValidator.
+func (v Decimal) Validate(value interface{}) (interface{}, error) {
+	s, err := decimalText(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDecimal(s); err != nil {
+		return nil, err
+	}
+	if v.Precision > 0 {
+		// Rounding adds at most one digit, checked once rounded
+		if integer, _ := decimalDigits(s); integer > v.Precision-v.Scale+1 {
+			return nil, fmt.Errorf("more than %d digits before the decimal point", v.Precision-v.Scale)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errors.New("not a decimal")
+	}
+
+	scale := decimalScale(s)
+	if v.Precision > 0 {
+		scale = v.Scale
+	}
+	text := r.FloatString(scale)
+	if strings.Trim(text, "-0.") == "" {
+		text = strings.TrimPrefix(text, "-")
+	}
+
+	if v.Precision > 0 {
+		integer, _, _ := strings.Cut(strings.TrimPrefix(text, "-"), ".")
+		if integer = strings.TrimLeft(integer, "0"); len(integer) > v.Precision-v.Scale {
+			return nil, fmt.Errorf("more than %d digits before the decimal point", v.Precision-v.Scale)
+		}
+	}
+	return text, nil
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Decimal) Serialize(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v Decimal) LessFunc() schema.LessFunc {
+	return lessDecimal
+}
+
+func lessDecimal(value, other interface{}) bool {
+	s1, ok1 := value.(string)
+	s2, ok2 := other.(string)
+	if !ok1 || !ok2 {
+		return false
+	}
+	if checkDecimal(s1) != nil || checkDecimal(s2) != nil {
+		return false
+	}
+	r1, ok1 := new(big.Rat).SetString(s1)
+	r2, ok2 := new(big.Rat).SetString(s2)
+	return ok1 && ok2 && r1.Cmp(r2) < 0
+}
+
+// The limits of the NUMERIC type.
+const (
+	maxIntegerDigits  = 131072
+	maxFractionDigits = 16383
+)
+
+// checkDecimal checks that the decimal text s, possibly in exponent notation,
+// fits in a NUMERIC, without parsing its value: the cost of parsing and
+// formatting grows with the exponent.
+func checkDecimal(s string) error {
+	integer, err := decimalDigits(s)
+	if err != nil {
+		return err
+	}
+	if integer > maxIntegerDigits {
+		return fmt.Errorf("more than %d digits before the decimal point", maxIntegerDigits)
+	}
+	if decimalScale(s) > maxFractionDigits {
+		return fmt.Errorf("more than %d digits after the decimal point", maxFractionDigits)
+	}
+	return nil
+}
+
+// decimalDigits returns the number of significant digits before the decimal
+// point of the decimal text s, possibly in exponent notation.
+func decimalDigits(s string) (int, error) {
+	mantissa, exponent, hasExponent := strings.Cut(strings.ToLower(s), "e")
+	if len(mantissa) > 0 && (mantissa[0] == '-' || mantissa[0] == '+') {
+		mantissa = mantissa[1:]
+	}
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	digits := integer + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return 0, errors.New("not a decimal")
+	}
+
+	e := 0
+	if hasExponent {
+		unsigned := strings.TrimLeft(exponent, "+-")
+		if unsigned == "" || len(exponent)-len(unsigned) > 1 || strings.Trim(unsigned, "0123456789") != "" {
+			return 0, errors.New("not a decimal")
+		}
+		if len(strings.TrimLeft(unsigned, "0")) > 6 {
+			return 0, errors.New("exponent out of range")
+		}
+		e, _ = strconv.Atoi(exponent)
+	}
+
+	significant := strings.TrimLeft(digits, "0")
+	if strings.TrimRight(significant, "0") == "" {
+		return 0, nil
+	}
+	return max(0, len(integer)-(len(digits)-len(significant))+e), nil
+}
+
+// decimalText returns the text of the decimal value.
+func decimalText(value interface{}) (string, error) {
+	switch t := value.(type) {
+	case string:
+		return strings.TrimSpace(t), nil
+	case json.Number:
+		return t.String(), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(t), nil
+	}
+	return "", errors.New("not a decimal")
+}
+
+// decimalScale returns the number of digits of the fractional part of the
+// decimal text s, possibly in exponent notation.
+func decimalScale(s string) int {
+	mantissa, exponent, _ := strings.Cut(strings.ToLower(s), "e")
+	_, fraction, _ := strings.Cut(mantissa, ".")
+	scale := len(fraction)
+	if exponent != "" {
+		e, err := strconv.Atoi(exponent)
+		if err == nil {
+			scale -= e
+		}
+	}
+	if scale < 0 {
+		return 0
+	}
+	return scale
+}
+
+// Money validates amounts of money, stored as NUMERIC(19,<Scale>). Values
+// are strings, e.g. "12.50". The MONEY type is not used as its text depends
+// on the lc_monetary setting of the server.
+type Money struct {
+	// Scale is the number of decimals of the amounts, 2 when 0.
+	Scale int
+}
+
+func (v Money) decimal() Decimal {
+	scale := v.Scale
+	if scale <= 0 {
+		scale = 2
+	}
+	return Decimal{Precision: 19, Scale: scale}
+}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Money) PostgresType() string {
+	return v.decimal().PostgresType()
+}
+
+// Validate implements schema.FieldValidator.
+func (v Money) Validate(value interface{}) (interface{}, error) {
+	return v.decimal().Validate(value)
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Money) Serialize(value interface{}) (interface{}, error) {
+	return v.decimal().Serialize(value)
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v Money) LessFunc() schema.LessFunc {
+	return lessDecimal
+}
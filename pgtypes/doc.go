@@ -0,0 +1,9 @@
+// Package pgtypes provides rest-layer validators for PostgreSQL types. They
+// implement pgsql.PostgresTyper, so the classic and hybrid stores create
+// columns of their type and the stores cast the texts of JSONB payloads to
+// it in predicates and sorts.
+//
+// Values are kept in the form PostgreSQL reads and writes as text, e.g.
+// decimals as strings, so that they round-trip exactly through columns and
+// payloads. Serialize converts the values read from columns to that form.
+package pgtypes
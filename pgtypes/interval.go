@@ -0,0 +1,98 @@
+package pgtypes
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+var clockExpr = regexp.MustCompile(`^(-)?(\d+):([0-5]\d):([0-5]\d)(?:\.(\d{1,6}))?$`)
+
+// Interval validates durations, stored as INTERVAL. Values are strings in
+// the [-]HH:MM:SS[.ffffff] form PostgreSQL writes them in, and
+// time.Durations or their texts, e.g. "1h30m", are accepted as input.
+type Interval struct{}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Interval) PostgresType() string {
+	return "INTERVAL"
+}
+
+// Validate implements schema.FieldValidator.
+func (v Interval) Validate(value interface{}) (interface{}, error) {
+	switch t := value.(type) {
+	case time.Duration:
+		return formatInterval(t), nil
+	case string:
+		if d, ok := parseInterval(t); ok {
+			return formatInterval(d), nil
+		}
+		if d, err := time.ParseDuration(t); err == nil {
+			return formatInterval(d), nil
+		}
+	}
+	return nil, errors.New("not an interval")
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Interval) Serialize(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v Interval) LessFunc() schema.LessFunc {
+	return func(value, other interface{}) bool {
+		s1, ok1 := value.(string)
+		s2, ok2 := other.(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		d1, ok1 := parseInterval(s1)
+		d2, ok2 := parseInterval(s2)
+		return ok1 && ok2 && d1 < d2
+	}
+}
+
+// formatInterval returns the text of d, to the microsecond.
+func formatInterval(d time.Duration) string {
+	d = d.Round(time.Microsecond)
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	text := fmt.Sprintf("%s%02d:%02d:%02d", sign, d/time.Hour, d/time.Minute%60, d/time.Second%60)
+	if micros := d % time.Second / time.Microsecond; micros != 0 {
+		text += strings.TrimRight(fmt.Sprintf(".%06d", micros), "0")
+	}
+	return text
+}
+
+// parseInterval parses the [-]HH:MM:SS[.ffffff] text of an interval.
+func parseInterval(s string) (time.Duration, bool) {
+	m := clockExpr.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	hours, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	minutes, _ := strconv.Atoi(m[3])
+	seconds, _ := strconv.Atoi(m[4])
+	micros, _ := strconv.Atoi((m[5] + "000000")[:6])
+
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(micros)*time.Microsecond
+	if m[1] != "" {
+		d = -d
+	}
+	return d, true
+}
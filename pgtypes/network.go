@@ -0,0 +1,94 @@
+package pgtypes
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Inet validates host addresses with an optional network prefix, stored as
+// INET, e.g. "192.168.0.1" or "192.168.0.1/24". The prefix is left out when
+// it covers the whole address, as PostgreSQL writes it.
+type Inet struct{}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Inet) PostgresType() string {
+	return "INET"
+}
+
+// Validate implements schema.FieldValidator.
+func (v Inet) Validate(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.New("not an IP address")
+		}
+		return ip.String(), nil
+	}
+
+	ip, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, errors.New("not an IP address")
+	}
+	if ones, bits := network.Mask.Size(); ones != bits {
+		return ip.String() + "/" + strconv.Itoa(ones), nil
+	}
+	return ip.String(), nil
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Inet) Serialize(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
+
+// CIDR validates network addresses, stored as CIDR, e.g. "10.0.0.0/8". An
+// address without prefix is a network of a single host.
+type CIDR struct{}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v CIDR) PostgresType() string {
+	return "CIDR"
+}
+
+// Validate implements schema.FieldValidator.
+func (v CIDR) Validate(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.New("not a network address")
+		}
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+
+	ip, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, errors.New("not a network address")
+	}
+	if !ip.Equal(network.IP) {
+		return nil, errors.New("bits set to the right of the network mask")
+	}
+	return network.String(), nil
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v CIDR) Serialize(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,184 @@
+package pgtypes
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+type validator interface {
+	schema.FieldValidator
+	schema.FieldSerializer
+	PostgresType() string
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator
+		value     interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{"decimal string", Decimal{}, "012.50", "12.50", false},
+		{"decimal exponent", Decimal{}, "1.5e2", "150", false},
+		{"decimal float", Decimal{}, 0.1, "0.1", false},
+		{"decimal json number", Decimal{}, json.Number("-3.25"), "-3.25", false},
+		{"decimal scale", Decimal{Precision: 5, Scale: 2}, "1.005", "1.01", false},
+		{"decimal padded", Decimal{Precision: 5, Scale: 2}, 7, "7.00", false},
+		{"decimal negative zero", Decimal{Precision: 5, Scale: 2}, "-0.001", "0.00", false},
+		{"decimal overflow", Decimal{Precision: 5, Scale: 2}, "1000", nil, true},
+		{"decimal invalid", Decimal{}, "12,5", nil, true},
+		{"decimal fraction", Decimal{}, "1/3", nil, true},
+		{"decimal oversized exponent", Decimal{}, "1e1000000", nil, true},
+		{"decimal too many digits", Decimal{}, "1e131072", nil, true},
+		{"decimal max digits", Decimal{}, "0.1e131072", "1" + strings.Repeat("0", 131071), false},
+		{"decimal oversized scale", Decimal{}, "1e-16384", nil, true},
+		{"decimal oversized zero scale", Decimal{}, "0e-999999", nil, true},
+		{"decimal precision exponent", Decimal{Precision: 5, Scale: 2}, "1e100000", nil, true},
+		{"money", Money{}, "19.9", "19.90", false},
+		{"money scale", Money{Scale: 3}, 1, "1.000", false},
+		{"uuid", UUID{}, "{A0EEBC999C0B4EF8BB6D6BB9BD380A11}", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", false},
+		{"uuid invalid", UUID{}, "a0eebc99-9c0b", nil, true},
+		{"date", Date{}, "2020-02-29", "2020-02-29", false},
+		{"date time", Date{}, "2020-02-29T23:00:00-05:00", "2020-02-29", false},
+		{"date value", Date{}, time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC), "2020-02-29", false},
+		{"date invalid", Date{}, "2019-02-29", nil, true},
+		{"interval duration", Interval{}, "26h30m1.5s", "26:30:01.5", false},
+		{"interval clock", Interval{}, "-01:02:03", "-01:02:03", false},
+		{"interval value", Interval{}, 90 * time.Second, "00:01:30", false},
+		{"interval invalid", Interval{}, "1 day", nil, true},
+		{"inet", Inet{}, "192.168.0.1", "192.168.0.1", false},
+		{"inet prefix", Inet{}, "192.168.0.1/24", "192.168.0.1/24", false},
+		{"inet full prefix", Inet{}, "2001:DB8::1/128", "2001:db8::1", false},
+		{"inet invalid", Inet{}, "192.168.0.256", nil, true},
+		{"cidr", CIDR{}, "10.0.0.0/8", "10.0.0.0/8", false},
+		{"cidr host", CIDR{}, "10.1.2.3", "10.1.2.3/32", false},
+		{"cidr host bits", CIDR{}, "10.1.0.0/8", nil, true},
+		{"citext", CIText{}, "John", "John", false},
+		{"bytea", Bytea{}, "aGVsbG8=", Bytes("hello"), false},
+		{"bytea bytes", Bytea{}, []byte("hello"), Bytes("hello"), false},
+		{"bytea too long", Bytea{MaxLen: 2}, []byte("hello"), nil, true},
+		{"bytea invalid", Bytea{}, "not base64", nil, true},
+		{"point", Point{}, map[string]interface{}{"lat": 48.8566, "lng": json.Number("2.3522")}, LatLng{Lat: 48.8566, Lng: 2.3522}, false},
+		{"point column", Point{}, "(2.3522,48.8566)", LatLng{Lat: 48.8566, Lng: 2.3522}, false},
+		{"point out of range", Point{}, map[string]interface{}{"lat": 91.0, "lng": 0.0}, nil, true},
+		{"point invalid", Point{}, map[string]interface{}{"lat": "north"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.validator.Validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator
+		value     interface{}
+		want      interface{}
+	}{
+		{"decimal", Decimal{}, []byte("12.50"), "12.50"},
+		{"date column", Date{}, time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC), "2020-02-29"},
+		{"date payload", Date{}, "2020-02-29", "2020-02-29"},
+		{"bytea column", Bytea{}, []byte("hello"), "aGVsbG8="},
+		{"bytea payload", Bytea{}, `\x68656c6c6f`, "aGVsbG8="},
+		{"bytea serialized", Bytea{}, "aGVsbG8=", "aGVsbG8="},
+		{"bytea value", Bytea{}, Bytes("hello"), "aGVsbG8="},
+		{"point column", Point{}, []byte("(2.5,-1)"), map[string]interface{}{"lat": -1.0, "lng": 2.5}},
+		{"point value", Point{}, LatLng{Lat: -1, Lng: 2.5}, map[string]interface{}{"lat": -1.0, "lng": 2.5}},
+		{"point payload", Point{}, map[string]interface{}{"lat": -1.0, "lng": 2.5}, map[string]interface{}{"lat": -1.0, "lng": 2.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.validator.Serialize(tt.value)
+			if err != nil {
+				t.Fatalf("Serialize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Serialize() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresType(t *testing.T) {
+	tests := []struct {
+		validator validator
+		want      string
+	}{
+		{Decimal{}, "NUMERIC"},
+		{Decimal{Precision: 10, Scale: 2}, "NUMERIC(10,2)"},
+		{Money{}, "NUMERIC(19,2)"},
+		{UUID{}, "UUID"},
+		{Date{}, "DATE"},
+		{Interval{}, "INTERVAL"},
+		{Inet{}, "INET"},
+		{CIDR{}, "CIDR"},
+		{CIText{}, "CITEXT"},
+		{Bytea{}, "BYTEA"},
+		{Point{}, "POINT"},
+	}
+	for _, tt := range tests {
+		if got := tt.validator.PostgresType(); got != tt.want {
+			t.Errorf("PostgresType() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestLessFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparator schema.FieldComparator
+		value      interface{}
+		other      interface{}
+	}{
+		{"decimal", Decimal{}, "9.5", "10"},
+		{"interval", Interval{}, "09:00:00", "100:00:00"},
+		{"citext", CIText{}, "apple", "Banana"},
+		{"bytea", Bytea{}, Bytes{1}, Bytes{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			less := tt.comparator.LessFunc()
+			if !less(tt.value, tt.other) || less(tt.other, tt.value) {
+				t.Errorf("LessFunc() does not order %v before %v", tt.value, tt.other)
+			}
+		})
+	}
+}
+
+func TestLessFunc_oversizedDecimal(t *testing.T) {
+	less := Decimal{}.LessFunc()
+	if less("1e-1000000", "1e1000000") || less("1", "1e200000") {
+		t.Error("LessFunc() compared decimals out of the NUMERIC range")
+	}
+}
+
+func TestBytesMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(map[string]interface{}{"data": Bytes("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"data":"\\x6869"}`; string(b) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", b, want)
+	}
+}
+
+func TestLatLngValue(t *testing.T) {
+	v, err := LatLng{Lat: 48.8566, Lng: 2.3522}.Value()
+	if err != nil || v != "(2.3522,48.8566)" {
+		t.Errorf("Value() = %v, %v, want (2.3522,48.8566)", v, err)
+	}
+}
@@ -0,0 +1,116 @@
+package pgtypes
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Point validates geographic locations, stored as POINT with the longitude
+// as x and the latitude as y, and as {"lat", "lng"} objects in JSONB
+// payloads. Values are LatLng, and such objects are accepted as input.
+// Serialize returns them as such objects.
+//
+// Point fields are filtered with the pgsql.WithinBox and pgsql.Near
+// predicates, and sorted by distance to the center of the latter.
+type Point struct{}
+
+// LatLng is the value of a Point field, in degrees.
+type LatLng struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Coordinates returns the latitude and the longitude of p.
+func (p LatLng) Coordinates() (lat, lng float64) {
+	return p.Lat, p.Lng
+}
+
+// Value implements driver.Valuer.
+func (p LatLng) Value() (driver.Value, error) {
+	return "(" + strconv.FormatFloat(p.Lng, 'g', -1, 64) + "," + strconv.FormatFloat(p.Lat, 'g', -1, 64) + ")", nil
+}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v Point) PostgresType() string {
+	return "POINT"
+}
+
+// Validate implements schema.FieldValidator.
+func (v Point) Validate(value interface{}) (interface{}, error) {
+	var p LatLng
+	switch t := value.(type) {
+	case LatLng:
+		p = t
+	case map[string]interface{}:
+		lat, ok1 := coordinate(t["lat"])
+		lng, ok2 := coordinate(t["lng"])
+		if !ok1 || !ok2 {
+			return nil, errors.New("not a lat/lng object")
+		}
+		p = LatLng{Lat: lat, Lng: lng}
+	case string:
+		var err error
+		if p, err = parsePoint(t); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("not a lat/lng object")
+	}
+
+	if p.Lat < -90 || p.Lat > 90 {
+		return nil, errors.New("latitude out of range")
+	}
+	if p.Lng < -180 || p.Lng > 180 {
+		return nil, errors.New("longitude out of range")
+	}
+	return p, nil
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v Point) Serialize(value interface{}) (interface{}, error) {
+	switch t := value.(type) {
+	case LatLng:
+		return map[string]interface{}{"lat": t.Lat, "lng": t.Lng}, nil
+	case []byte:
+		return v.Serialize(string(t))
+	case string:
+		// Read from a point column
+		p, err := parsePoint(t)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"lat": p.Lat, "lng": p.Lng}, nil
+	}
+	return value, nil
+}
+
+// parsePoint parses the (x,y) text of a point.
+func parsePoint(s string) (LatLng, error) {
+	x, y, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(s, "("), ")"), ",")
+	if !ok {
+		return LatLng{}, fmt.Errorf("invalid point %q", s)
+	}
+	lng, err1 := strconv.ParseFloat(strings.TrimSpace(x), 64)
+	lat, err2 := strconv.ParseFloat(strings.TrimSpace(y), 64)
+	if err1 != nil || err2 != nil {
+		return LatLng{}, fmt.Errorf("invalid point %q", s)
+	}
+	return LatLng{Lat: lat, Lng: lng}, nil
+}
+
+func coordinate(value interface{}) (float64, bool) {
+	switch t := value.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,54 @@
+package pgtypes
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+var uuidExpr = regexp.MustCompile(`^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$`)
+
+// UUID validates UUIDs, stored as UUID. Values are lower case hyphenated
+// strings, as PostgreSQL writes them, and upper case, braced or unhyphenated
+// UUIDs are accepted as input.
+type UUID struct{}
+
+// PostgresType implements pgsql.PostgresTyper.
+func (v UUID) PostgresType() string {
+	return "UUID"
+}
+
+// Validate implements schema.FieldValidator.
+func (v UUID) Validate(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	s = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}"))
+	if !uuidExpr.MatchString(s) {
+		return nil, errors.New("not a UUID")
+	}
+	s = strings.ReplaceAll(s, "-", "")
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+// Serialize implements schema.FieldSerializer.
+func (v UUID) Serialize(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+	return value, nil
+}
+
+// LessFunc implements schema.FieldComparator.
+func (v UUID) LessFunc() schema.LessFunc {
+	return lessString
+}
+
+func lessString(value, other interface{}) bool {
+	s1, ok1 := value.(string)
+	s2, ok2 := other.(string)
+	return ok1 && ok2 && s1 < s2
+}
@@ -0,0 +1,136 @@
+package pgsql
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the default upper bounds, in seconds, of the
+// buckets of the operation durations.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics keeping the operations of the stores in a
+// pgsql_operations_total counter and a pgsql_operation_duration_seconds
+// histogram, labeled by table, operation and outcome. It serves them over
+// HTTP in the Prometheus text format, e.g. on /metrics, without depending on
+// the Prometheus client.
+type PrometheusMetrics struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	operations map[operationLabels]*operationMetrics
+}
+
+type operationLabels struct {
+	table, operation, outcome string
+}
+
+type operationMetrics struct {
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// NewPrometheusMetrics returns a PrometheusMetrics whose histogram has
+// buckets of the given upper bounds, in seconds, DefaultDurationBuckets if
+// none.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		buckets:    buckets,
+		operations: map[operationLabels]*operationMetrics{},
+	}
+}
+
+// ObserveOperation implements Metrics.
+func (m *PrometheusMetrics) ObserveOperation(table, operation, outcome string, duration time.Duration) {
+	labels := operationLabels{table: table, operation: operation, outcome: outcome}
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metrics, ok := m.operations[labels]
+	if !ok {
+		metrics = &operationMetrics{buckets: make([]uint64, len(m.buckets))}
+		m.operations[labels] = metrics
+	}
+	metrics.count++
+	metrics.sum += seconds
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			metrics.buckets[i]++
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	labels := make([]operationLabels, 0, len(m.operations))
+	metrics := make(map[operationLabels]operationMetrics, len(m.operations))
+	for l, o := range m.operations {
+		labels = append(labels, l)
+		metrics[l] = operationMetrics{count: o.count, sum: o.sum, buckets: append([]uint64(nil), o.buckets...)}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := labels[i], labels[j]
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.outcome < b.outcome
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP pgsql_operations_total Operations of the stores.\n")
+	b.WriteString("# TYPE pgsql_operations_total counter\n")
+	for _, l := range labels {
+		fmt.Fprintf(&b, "pgsql_operations_total{%s} %d\n", l.format(), metrics[l].count)
+	}
+
+	b.WriteString("# HELP pgsql_operation_duration_seconds Duration of the operations of the stores.\n")
+	b.WriteString("# TYPE pgsql_operation_duration_seconds histogram\n")
+	for _, l := range labels {
+		o := metrics[l]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(&b, "pgsql_operation_duration_seconds_bucket{%s,le=%q} %d\n", l.format(), strconv.FormatFloat(bound, 'g', -1, 64), o.buckets[i])
+		}
+		fmt.Fprintf(&b, "pgsql_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l.format(), o.count)
+		fmt.Fprintf(&b, "pgsql_operation_duration_seconds_sum{%s} %s\n", l.format(), strconv.FormatFloat(o.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "pgsql_operation_duration_seconds_count{%s} %d\n", l.format(), o.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (l operationLabels) format() string {
+	return fmt.Sprintf(`table="%s",operation="%s",outcome="%s"`, escapeLabel(l.table), escapeLabel(l.operation), escapeLabel(l.outcome))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value of the Prometheus text format.
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
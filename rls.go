@@ -0,0 +1,28 @@
+package pgsql
+
+// RowSecurity describes the row level security configuration Migrate applies
+// to a store table.
+type RowSecurity struct {
+	// Force applies the policies to the table owner as well.
+	Force bool
+	// Policies are dropped and recreated on every Migrate.
+	Policies []Policy
+}
+
+// Policy is the declarative description of a CREATE POLICY statement.
+type Policy struct {
+	// Name of the policy, unique per table.
+	Name string
+	// Restrictive creates an AS RESTRICTIVE policy instead of a permissive
+	// one.
+	Restrictive bool
+	// Command is one of ALL, SELECT, INSERT, UPDATE or DELETE. Defaults to
+	// ALL.
+	Command string
+	// Roles the policy applies to. Defaults to PUBLIC.
+	Roles []string
+	// Using is the SQL expression rows must satisfy to be visible.
+	Using string
+	// WithCheck is the SQL expression new rows must satisfy to be written.
+	WithCheck string
+}
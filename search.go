@@ -0,0 +1,58 @@
+package pgsql
+
+import (
+	"fmt"
+
+	"github.com/rs/rest-layer/schema"
+)
+
+const (
+	// SearchColumn is the generated column holding the search document of
+	// a table, built by Migrate from its Text fields.
+	SearchColumn = "search_vector"
+	// DefaultSearchLanguage is the text search configuration used when
+	// none is given.
+	DefaultSearchLanguage = "simple"
+)
+
+// Text is a string validator including the field in the search document of
+// the table. Migrate adds the document as a generated tsvector column with a
+// GIN index; it is not rebuilt when the Text fields change later on.
+type Text struct {
+	schema.String
+	// Language is the text search configuration of the field,
+	// DefaultSearchLanguage if empty.
+	Language string
+	// Weight is the weight of the field in the ranking, from "A" (highest)
+	// to "D". "D" if empty.
+	Weight string
+}
+
+// PostgresType implements PostgresTyper.
+func (t *Text) PostgresType() string {
+	if t.MaxLen > 0 {
+		return fmt.Sprintf("VARCHAR(%d)", t.MaxLen)
+	}
+	return "VARCHAR"
+}
+
+// Search is the validator of the search field of a schema, which is never
+// stored. Filtering on it with a string, e.g. {q: "apple -pie"}, matches the
+// items whose search document matches the query in websearch_to_tsquery
+// syntax. Sorting on it orders the matches by rank, most relevant first when
+// reversed.
+type Search struct {
+	schema.String
+	// Language is the text search configuration of the queries,
+	// DefaultSearchLanguage if empty.
+	Language string
+}
+
+// SearchField is a common schema field configuration for the search field.
+var SearchField = schema.Field{
+	Description: "Full-text search on the item's text fields",
+	ReadOnly:    true,
+	Filterable:  true,
+	Sortable:    true,
+	Validator:   &Search{},
+}
@@ -0,0 +1,32 @@
+package pgsql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// SettingsPrefix is the namespace of the custom settings applied from
+// SessionSettings.Values.
+const SettingsPrefix = "app"
+
+// SessionSettings are applied with SET LOCAL at the start of every
+// transaction run by a store, so row level security policies can rely on
+// them.
+type SessionSettings struct {
+	// Role, when not empty, is applied with SET LOCAL ROLE.
+	Role string
+	// Values are applied as SET LOCAL app.<key> = <value>.
+	Values map[string]string
+}
+
+// SessionSettingsFunc extracts the session settings of a request from its
+// context. Returning an error aborts the statement.
+type SessionSettingsFunc func(ctx context.Context) (SessionSettings, error)
+
+// Setting returns the SQL expression reading the custom setting key, to be
+// used in row level security policies. A missing setting reads as NULL.
+func Setting(key string) string {
+	return fmt.Sprintf("current_setting(%s, true)", pq.QuoteLiteral(SettingsPrefix+"."+key))
+}
@@ -0,0 +1,15 @@
+package pgsql
+
+import "testing"
+
+func TestSetting(t *testing.T) {
+	tests := map[string]string{
+		"tenant": "current_setting('app.tenant', true)",
+		"o'neil": "current_setting('app.o''neil', true)",
+	}
+	for key, want := range tests {
+		if got := Setting(key); got != want {
+			t.Errorf("Setting(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pgsql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/rest-layer/schema/query"
+)
+
+// SlowQueries configures the capture of the statements of a store exceeding
+// a duration, with the plans of the slow reads.
+type SlowQueries struct {
+	// Threshold is the duration from which a statement is slow.
+	Threshold time.Duration
+	// Analyze explains the slow reads with EXPLAIN ANALYZE, running them
+	// once more to report the actual timings. Keep it out of production.
+	Analyze bool
+	// Report is called with every slow statement, once its operation is
+	// done.
+	Report func(ctx context.Context, slow *SlowQuery)
+}
+
+// SlowQuery is a statement of a store that exceeded the SlowQueries
+// threshold.
+type SlowQuery struct {
+	// Operation is the store operation running the statement, e.g. "Find".
+	Operation string
+	// Table is the table of the store.
+	Table string
+	SQL   string
+	Args  []any
+	// Query is the rest-layer query the statement was built from, nil for
+	// the operations taking none, such as Insert or Update.
+	Query *query.Query
+	// Duration is the time the statement took, reading the rows excluded.
+	Duration time.Duration
+	// Plan is the output of EXPLAIN (FORMAT JSON) for the statement. Only
+	// plain reads are explained, not the ones locking rows or calling
+	// functions such as pg_notify, and only when their operation succeeded:
+	// Plan is nil for other statements. Within a transaction, the statement
+	// is explained in a savepoint, so a failure does not abort it.
+	Plan json.RawMessage
+	// Err is the error explaining the statement.
+	Err error
+}
+
+// WithSlowQueries makes the store report the statements exceeding
+// sq.Threshold to sq.Report, with the plans of the slow reads.
+func WithSlowQueries(sq SlowQueries) Option {
+	return func(o *Options) {
+		o.SlowQueries = &sq
+	}
+}
@@ -0,0 +1,49 @@
+package pgsql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/rest-layer/resource"
+)
+
+// DeletedColumn is the column holding the deletion time of soft deleted rows.
+const DeletedColumn = "deleted_at"
+
+// ErrSoftDeleteDisabled is returned by SoftDeleter methods of stores created
+// without WithSoftDelete.
+var ErrSoftDeleteDisabled = errors.New("soft delete is not enabled on this store")
+
+// SoftDeleter is implemented by stores created with WithSoftDelete.
+type SoftDeleter interface {
+	// Restore undeletes a soft deleted item. The etag of the stored item
+	// must match the one of the provided item.
+	Restore(ctx context.Context, item *resource.Item) error
+	// Purge permanently removes the rows soft deleted for longer than
+	// retention and returns their number.
+	Purge(ctx context.Context, retention time.Duration) (int, error)
+}
+
+// WithSoftDelete makes Delete and Clear set the deleted_at column instead of
+// removing rows, and makes reads skip soft deleted rows unless the context
+// was created with IncludeDeleted.
+func WithSoftDelete() Option {
+	return func(o *Options) {
+		o.SoftDelete = true
+	}
+}
+
+type includeDeletedKey struct{}
+
+// IncludeDeleted returns a context making reads of soft delete stores return
+// soft deleted rows as well.
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// IncludeDeletedFromContext tells if ctx was created with IncludeDeleted.
+func IncludeDeletedFromContext(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return include
+}
@@ -0,0 +1,71 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// DBResolver returns the database the statements of a request must run on.
+type DBResolver func(ctx context.Context) (*sql.DB, error)
+
+// TenantPools is a DBResolver backed by one connection pool per tenant. Pools
+// are opened on first use and kept for the lifetime of the TenantPools.
+type TenantPools struct {
+	// Tenant extracts the tenant identifier of a request from its context.
+	Tenant func(ctx context.Context) (string, error)
+	// Open opens the connection pool of a tenant database.
+	Open func(tenant string) (*sql.DB, error)
+
+	mu    sync.RWMutex
+	pools map[string]*sql.DB
+}
+
+// Resolve returns the connection pool of the tenant found in ctx, opening it
+// if needed. It can be passed as a DBResolver.
+func (p *TenantPools) Resolve(ctx context.Context) (*sql.DB, error) {
+	tenant, err := p.Tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	db, ok := p.pools[tenant]
+	p.mu.RUnlock()
+	if ok {
+		return db, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if db, ok := p.pools[tenant]; ok {
+		return db, nil
+	}
+
+	db, err = p.Open(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if p.pools == nil {
+		p.pools = make(map[string]*sql.DB)
+	}
+	p.pools[tenant] = db
+
+	return db, nil
+}
+
+// Close closes all the opened pools.
+func (p *TenantPools) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for tenant, db := range p.pools {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(p.pools, tenant)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,19 @@
+package pgsql
+
+// WithTimeZone makes the store keep times unambiguous:
+//
+//   - schema.Time fields, the update times and the times of soft deletes,
+//     history and outbox events are stored in TIMESTAMPTZ columns
+//   - times are converted to UTC before being encoded in JSONB payloads
+//   - the times of JSONB payloads are cast to TIMESTAMPTZ in predicates and
+//     sorts, so that they compare as instants
+//   - the times read from columns are returned in UTC
+//
+// Migrate converts the TIMESTAMP columns of existing tables to TIMESTAMPTZ,
+// taking their values as UTC times. The times already stored in JSONB
+// payloads are not rewritten.
+func WithTimeZone() Option {
+	return func(o *Options) {
+		o.TimeZone = true
+	}
+}